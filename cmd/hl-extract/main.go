@@ -0,0 +1,321 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+
+// hl-extract extracts Stoplight collections to files, one per collection, without a Jitsu pipeline:
+//
+//	hl-extract -config stoplight.yaml -collections contacts,opportunities -from 2023-03-01 -to 2023-03-31 -out ./extract
+//
+// Files are newline delimited JSON by default, -format csv writes CSV files (-delimiter ';' for spreadsheets
+// expecting semicolons) with nested objects flattened into columns and -format parquet and -format avro write
+// Parquet and Avro files with columns typed from the collection schema
+//
+// With -out s3://bucket/prefix or gs://bucket/prefix, records are uploaded to S3 or Google Cloud Storage under
+// <prefix>/<collection>/date=YYYY-MM-DD/ with the default AWS or Google application credentials, gzip compressed
+// with -compression gzip (ndjson and csv)
+//
+// With -backfill, the collections are backfilled by the driver serving the backfill API at the url instead:
+//
+//	hl-extract -backfill http://jitsu:8001/stoplight/backfill -collections opportunities -from 2023-03-01 -to 2023-03-31
+//
+// The config file is the Stoplight source config (JSON, or YAML for .yaml and .yml files). Records of collections
+// requested by time interval are extracted day by day, the other ones are filtered by update (or creation) time.
+// Records without update nor creation time (calendars, pipelines, custom fields...) are extracted in full.
+// -collections custom_objects extracts every custom object of the location, to custom_objects_<key> files
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+	"github.com/jitsucom/jitsu/server/drivers/stoplight"
+	"github.com/jitsucom/jitsu/server/jsonutils"
+	"gopkg.in/yaml.v3"
+)
+
+const dateLayout = "2006-01-02"
+
+func main() {
+	configPath := flag.String("config", "", "Stoplight source config file (JSON or YAML)")
+	collections := flag.String("collections", "", "comma separated collections to extract")
+	from := flag.String("from", time.Now().UTC().Format(dateLayout), "first day to extract (2006-01-02)")
+	to := flag.String("to", time.Now().UTC().Format(dateLayout), "last day to extract (2006-01-02)")
+	out := flag.String("out", ".", "output directory, s3://bucket/prefix or gs://bucket/prefix")
+	format := flag.String("format", stoplight.FormatNdjson, "output format: ndjson, csv, parquet or avro")
+	delimiter := flag.String("delimiter", ",", "csv delimiter")
+	compression := flag.String("compression", stoplight.CompressionNone, "s3 and gs output compression: none or gzip")
+	backfill := flag.String("backfill", "", "backfill API url: enqueue backfills instead of extracting")
+	flag.Parse()
+
+	if *backfill != "" {
+		err := enqueueBackfills(*backfill, *collections, *from, *to)
+		if err != nil {
+			log.Fatalf("hl-extract: %v", err)
+		}
+		return
+	}
+
+	options := &outputOptions{dir: *out, format: *format, compression: *compression}
+	if *format == stoplight.FormatCsv {
+		runes := []rune(*delimiter)
+		if len(runes) != 1 {
+			log.Fatalf("hl-extract: -delimiter must be a single character")
+		}
+		options.delimiter = runes[0]
+	}
+
+	err := run(*configPath, *collections, *from, *to, options)
+	if err != nil {
+		log.Fatalf("hl-extract: %v", err)
+	}
+}
+
+// outputOptions are the output directory (or bucket location) and format of the extracted files
+type outputOptions struct {
+	dir         string
+	format      string
+	delimiter   rune
+	compression string
+}
+
+// s3Config returns the S3 loader configuration of s3://bucket/prefix outputs
+func (oo *outputOptions) s3Config() (*stoplight.S3LoaderConfig, bool) {
+	if !strings.HasPrefix(oo.dir, "s3://") {
+		return nil, false
+	}
+	location := strings.SplitN(strings.TrimPrefix(oo.dir, "s3://"), "/", 2)
+	config := &stoplight.S3LoaderConfig{Bucket: location[0], Format: oo.format, Compression: oo.compression}
+	if len(location) == 2 {
+		config.Prefix = location[1]
+	}
+	return config, true
+}
+
+// gcsConfig returns the GCS loader configuration of gs://bucket/prefix outputs
+func (oo *outputOptions) gcsConfig() (*stoplight.GCSLoaderConfig, bool) {
+	if !strings.HasPrefix(oo.dir, "gs://") {
+		return nil, false
+	}
+	location := strings.SplitN(strings.TrimPrefix(oo.dir, "gs://"), "/", 2)
+	config := &stoplight.GCSLoaderConfig{Bucket: location[0], Auth: stoplight.GCSAuthWorkloadIdentity,
+		Format: oo.format, Compression: oo.compression}
+	if len(location) == 2 {
+		config.Prefix = location[1]
+	}
+	return config, true
+}
+
+// loader returns the loader uploading the records of the collection to the bucket of s3:// and gs:// outputs
+func (oo *outputOptions) loader(collection string) (base.ObjectsLoader, bool, error) {
+	if config, ok := oo.s3Config(); ok {
+		loader, err := stoplight.NewS3Loader(config, collection)
+		return loader, true, err
+	}
+	if config, ok := oo.gcsConfig(); ok {
+		loader, err := stoplight.NewGCSLoader(context.Background(), config, collection)
+		return loader, true, err
+	}
+	return nil, false, nil
+}
+
+// newWriter returns the record writer of the output format for the collection
+func (oo *outputOptions) newWriter(w io.Writer, collection string) (stoplight.RecordWriter, error) {
+	switch oo.format {
+	case stoplight.FormatNdjson:
+		return stoplight.NewNdjsonWriter(w), nil
+	case stoplight.FormatCsv:
+		return stoplight.NewCsvWriter(w, oo.delimiter, ""), nil
+	case stoplight.FormatParquet:
+		return stoplight.NewParquetWriter(w, collection), nil
+	case stoplight.FormatAvro:
+		return stoplight.NewAvroWriter(w, collection), nil
+	default:
+		return nil, fmt.Errorf("-format %q is not supported", oo.format)
+	}
+}
+
+func run(configPath string, collections string, from string, to string, out *outputOptions) error {
+	if configPath == "" || collections == "" {
+		return errors.New("-config and -collections are required")
+	}
+	fromDay, err := time.Parse(dateLayout, from)
+	if err != nil {
+		return fmt.Errorf("-from is invalid: %v", err)
+	}
+	toDay, err := time.Parse(dateLayout, to)
+	if err != nil {
+		return fmt.Errorf("-to is invalid: %v", err)
+	}
+
+	if _, err := out.newWriter(ioutil.Discard, ""); err != nil {
+		return err
+	}
+	bucket := false
+	if s3Config, ok := out.s3Config(); ok {
+		if err := s3Config.Validate(); err != nil {
+			return err
+		}
+		bucket = true
+	}
+	if gcsConfig, ok := out.gcsConfig(); ok {
+		if err := gcsConfig.Validate(); err != nil {
+			return err
+		}
+		bucket = true
+	}
+
+	sourceConfig, err := readSourceConfig(configPath)
+	if err != nil {
+		return err
+	}
+	if !bucket {
+		err = os.MkdirAll(out.dir, 0755)
+		if err != nil {
+			return err
+		}
+	}
+
+	var collectionTypes []string
+	for _, collection := range strings.Split(collections, ",") {
+		collection = strings.TrimSpace(collection)
+		if collection != "" {
+			collectionTypes = append(collectionTypes, collection)
+		}
+	}
+	listed, err := stoplight.ListCollections(sourceConfig, collectionTypes)
+	if err != nil {
+		return err
+	}
+
+	for _, collection := range listed {
+		start := time.Now()
+		extracted, err := extract(sourceConfig, collection, fromDay, toDay, out)
+		if err != nil {
+			return fmt.Errorf("%s: %v", collection.Name, err)
+		}
+		log.Printf("%s: %d records extracted in %s", collection.Name, extracted, time.Since(start).Round(time.Millisecond))
+	}
+
+	return nil
+}
+
+// readSourceConfig reads and validates the Stoplight source config file
+func readSourceConfig(path string) (*base.SourceConfig, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &config)
+	default:
+		err = json.Unmarshal(b, &config)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing config %s: %v", path, err)
+	}
+
+	stoplightConfig := &stoplight.StoplightConfig{}
+	err = jsonutils.UnmarshalConfig(config, stoplightConfig)
+	if err != nil {
+		return nil, err
+	}
+	err = stoplightConfig.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &base.SourceConfig{SourceID: "hl-extract", Type: base.StoplightType, Config: config}, nil
+}
+
+// extract writes the records of the collection between the days to <out>/<collection name>.<format>, or uploads
+// them to the output bucket
+func extract(sourceConfig *base.SourceConfig, collection *base.Collection, from time.Time, to time.Time, out *outputOptions) (int, error) {
+	driver, err := stoplight.NewStoplight(context.Background(), sourceConfig, collection)
+	if err != nil {
+		return 0, err
+	}
+
+	loader, ok, err := out.loader(collection.Name)
+	if err != nil {
+		return 0, err
+	}
+	if ok {
+		return driver.(*stoplight.Stoplight).Backfill(collection.Type, from, to, func(_ string, objects []map[string]interface{}) error {
+			return loader.Load(objects, 0, 0, 0)
+		})
+	}
+
+	file, err := os.Create(filepath.Join(out.dir, collection.Name+"."+out.format))
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	writer, err := out.newWriter(file, collection.Type)
+	if err != nil {
+		return 0, err
+	}
+	extracted, err := driver.(*stoplight.Stoplight).Backfill(collection.Type, from, to, func(_ string, objects []map[string]interface{}) error {
+		return writer.Write(objects)
+	})
+	if err != nil {
+		return extracted, err
+	}
+
+	return extracted, writer.Close()
+}
+
+// enqueueBackfills enqueues the backfill of every collection between the days in the backfill API
+func enqueueBackfills(url string, collections string, from string, to string) error {
+	if collections == "" {
+		return errors.New("-collections is required")
+	}
+
+	for _, collection := range strings.Split(collections, ",") {
+		collection = strings.TrimSpace(collection)
+		if collection == "" {
+			continue
+		}
+
+		b, err := json.Marshal(&stoplight.BackfillJob{Collection: collection, From: from, To: to})
+		if err != nil {
+			return err
+		}
+		resp, err := http.Post(url, "application/json", bytes.NewReader(b))
+		if err != nil {
+			return err
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusAccepted {
+			return fmt.Errorf("%s: backfill API responded %d: %s", collection, resp.StatusCode, strings.TrimSpace(string(body)))
+		}
+
+		job := &stoplight.BackfillJob{}
+		err = json.Unmarshal(body, job)
+		if err != nil {
+			return err
+		}
+		log.Printf("%s: backfill %s %s - %s %s", collection, job.Id, job.From, job.To, job.Status)
+	}
+
+	return nil
+}
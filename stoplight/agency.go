@@ -0,0 +1,36 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+// GetSaasSubscriptions returns the subscription and rebilling data of the sub-accounts of a SaaS mode agency
+func (s *Stoplight) GetSaasSubscriptions() ([]map[string]interface{}, error) {
+	return s.getAllOffsets(fmt.Sprintf("%s/saas-api/public-api/saas-locations/%s", apiURL, s.config.CompanyId), "data")
+}
+
+// GetSnapshots returns the snapshot catalog (id, name, type) of the agency
+func (s *Stoplight) GetSnapshots() ([]map[string]interface{}, error) {
+	return s.getObjects(apiURL+"/snapshots/?companyId="+s.config.CompanyId, "snapshots")
+}
+
+// GetAuditLogs returns the audit logs of user actions in the location in the interval. An empty list is returned
+// when audit logs aren't exposed for the agency
+func (s *Stoplight) GetAuditLogs(interval *base.TimeInterval) ([]map[string]interface{}, error) {
+	url := fmt.Sprintf("%s/audit-logs/?companyId=%s&locationId=%s&startAt=%s&endAt=%s", apiURL, s.config.CompanyId, s.config.LocationId,
+		interval.LowerEndpoint().Format(time.RFC3339), interval.UpperEndpoint().Format(time.RFC3339))
+	logs, err := s.getAllOffsets(url, "logs")
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return logs, nil
+}
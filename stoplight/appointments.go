@@ -0,0 +1,129 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+// GetAppointmentNotes returns the notes of every appointment in the interval with their appointment_id
+func (s *Stoplight) GetAppointmentNotes(interval *base.TimeInterval) ([]map[string]interface{}, error) {
+	appointments, err := s.getAppointments(interval)
+	if err != nil {
+		return nil, err
+	}
+
+	var notes []map[string]interface{}
+	for _, appointment := range appointments {
+		url := fmt.Sprintf("%s/calendars/appointments/%v/notes", apiURL, appointment["id"])
+		appointmentNotes, err := s.getAllOffsets(url, "notes")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, note := range appointmentNotes {
+			note["appointment_id"] = appointment["id"]
+		}
+		notes = append(notes, appointmentNotes...)
+	}
+
+	if s.config.EnrichUsers {
+		err = s.enrichUsers(notes, "userId", "user")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return notes, nil
+}
+
+// getAppointments returns the appointments of every calendar of the location starting in the interval
+func (s *Stoplight) getAppointments(interval *base.TimeInterval) ([]map[string]interface{}, error) {
+	calendars, err := s.GetCalendars()
+	if err != nil {
+		return nil, err
+	}
+
+	var appointments []map[string]interface{}
+	for _, calendar := range calendars {
+		url := fmt.Sprintf("%s/calendars/events?locationId=%s&calendarId=%v&startTime=%d&endTime=%d", apiURL, s.config.LocationId,
+			calendar["id"], interval.LowerEndpoint().UnixMilli(), interval.UpperEndpoint().UnixMilli())
+		events, err := s.getObjects(url, "events")
+		if err != nil {
+			return nil, err
+		}
+		appointments = append(appointments, events...)
+	}
+
+	return appointments, nil
+}
+
+// resolveTimezones adds start_time_utc, start_time_local, end_time_utc, end_time_local and timezone columns
+// to the appointments. Times without offset are calendar local times: they are resolved against
+// the calendar timezone or the location one if the calendar doesn't define it
+func (s *Stoplight) resolveTimezones(appointments []map[string]interface{}) error {
+	calendars, err := s.calendarsById()
+	if err != nil {
+		return err
+	}
+	location, err := s.location()
+	if err != nil {
+		return err
+	}
+
+	for _, appointment := range appointments {
+		timezone, _ := calendars[fmt.Sprint(appointment["calendarId"])]["timezone"].(string)
+		if timezone == "" {
+			timezone, _ = location["timezone"].(string)
+		}
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			loc = time.UTC
+		}
+		appointment["timezone"] = loc.String()
+
+		for _, field := range []struct{ key, column string }{{"startTime", "start_time"}, {"endTime", "end_time"}} {
+			value, _ := appointment[field.key].(string)
+			t, ok := parseAppointmentTime(value, loc)
+			if !ok {
+				continue
+			}
+			appointment[field.column+"_utc"] = t.UTC().Format(time.RFC3339)
+			appointment[field.column+"_local"] = t.In(loc).Format("2006-01-02T15:04:05")
+		}
+	}
+
+	return nil
+}
+
+// addCalendars denormalizes the calendar_name and calendar_type of the appointments calendar from the calendars cache
+func (s *Stoplight) addCalendars(appointments []map[string]interface{}) error {
+	calendars, err := s.calendarsById()
+	if err != nil {
+		return err
+	}
+
+	for _, appointment := range appointments {
+		calendar := calendars[fmt.Sprint(appointment["calendarId"])]
+		appointment["calendar_name"] = calendar["name"]
+		appointment["calendar_type"] = calendar["calendarType"]
+	}
+
+	return nil
+}
+
+// parseAppointmentTime parses an appointment time with or without offset, times without offset are in loc
+func parseAppointmentTime(value string, loc *time.Location) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, true
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02 15:04:05"} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
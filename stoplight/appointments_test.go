@@ -0,0 +1,65 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+func TestResolveTimezones(t *testing.T) {
+	s := &Stoplight{
+		config:     &StoplightConfig{},
+		collection: &base.Collection{SourceID: "source", Type: ContactAppointmentsCollection},
+		calendarsCache: map[string]map[string]interface{}{
+			"cal1": {"id": "cal1", "timezone": "America/New_York"},
+			"cal2": {"id": "cal2"},
+		},
+		locationCache: map[string]interface{}{"timezone": "Europe/Paris"},
+	}
+	appointments := []map[string]interface{}{
+		{"id": "a1", "calendarId": "cal1", "startTime": "2023-07-01 09:00:00", "endTime": "2023-07-01T14:30:00Z"},
+		{"id": "a2", "calendarId": "cal2", "startTime": "2023-07-01T09:00:00"},
+	}
+
+	err := s.resolveTimezones(appointments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []map[string]interface{}{
+		{"timezone": "America/New_York", "start_time_utc": "2023-07-01T13:00:00Z", "start_time_local": "2023-07-01T09:00:00",
+			"end_time_utc": "2023-07-01T14:30:00Z", "end_time_local": "2023-07-01T10:30:00"},
+		{"timezone": "Europe/Paris", "start_time_utc": "2023-07-01T07:00:00Z", "start_time_local": "2023-07-01T09:00:00"},
+	}
+	for i, appointment := range appointments {
+		for column, value := range expected[i] {
+			if appointment[column] != value {
+				t.Errorf("appointment %v %s is %v, expected %v", appointment["id"], column, appointment[column], value)
+			}
+		}
+	}
+}
+
+func TestAddCalendars(t *testing.T) {
+	s := &Stoplight{
+		config:     &StoplightConfig{},
+		collection: &base.Collection{SourceID: "source", Type: ContactAppointmentsCollection},
+		calendarsCache: map[string]map[string]interface{}{
+			"cal1": {"id": "cal1", "name": "Demo", "calendarType": "round_robin"},
+		},
+	}
+	appointments := []map[string]interface{}{{"id": "a1", "calendarId": "cal1"}, {"id": "a2", "calendarId": "deleted"}}
+
+	err := s.addCalendars(appointments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if appointments[0]["calendar_name"] != "Demo" || appointments[0]["calendar_type"] != "round_robin" {
+		t.Errorf("appointment %v, expected the Demo round robin calendar", appointments[0])
+	}
+	if appointments[1]["calendar_name"] != nil {
+		t.Errorf("appointment %v of an unknown calendar has a calendar name", appointments[1])
+	}
+}
@@ -0,0 +1,37 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import "fmt"
+
+// GetAssociations returns the relations between records (contacts, companies, custom objects) of the location
+// as (from_id, from_object, to_id, to_object, relation_type) rows
+func (s *Stoplight) GetAssociations() ([]map[string]interface{}, error) {
+	associations, err := s.getAllOffsets(apiURL+"/associations/?locationId="+s.config.LocationId, "associations")
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []map[string]interface{}
+	for _, association := range associations {
+		url := fmt.Sprintf("%s/associations/relations?locationId=%s&associationId=%v", apiURL, s.config.LocationId, association["id"])
+		relations, err := s.getAllOffsets(url, "relations")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, relation := range relations {
+			rows = append(rows, map[string]interface{}{
+				"id":             relation["id"],
+				"from_id":        relation["firstRecordId"],
+				"from_object":    association["firstObjectKey"],
+				"to_id":          relation["secondRecordId"],
+				"to_object":      association["secondObjectKey"],
+				"relation_type":  association["key"],
+				"association_id": association["id"],
+			})
+		}
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,120 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/linkedin/goavro/v2"
+)
+
+const FormatAvro = "avro"
+
+var avroName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// avroWriter writes the records as a Snappy compressed Avro object container file embedding the schema. The
+// columns are typed from the collection schema and the first written records: columns appearing later are dropped
+type avroWriter struct {
+	w          io.Writer
+	collection string
+	writer     *goavro.OCFWriter
+	columns    []*column
+}
+
+// NewAvroWriter returns a record writer writing the records of the collection as Avro
+func NewAvroWriter(w io.Writer, collection string) RecordWriter {
+	return &avroWriter{w: w, collection: collection}
+}
+
+func (aw *avroWriter) Write(objects []map[string]interface{}) error {
+	if len(objects) == 0 {
+		return nil
+	}
+	if aw.writer == nil {
+		err := aw.open(objects)
+		if err != nil {
+			return err
+		}
+	}
+
+	records := make([]map[string]interface{}, 0, len(objects))
+	for _, object := range objects {
+		record := make(map[string]interface{}, len(aw.columns))
+		for _, column := range aw.columns {
+			value := columnValue(column.kind, object[column.name])
+			if value != nil {
+				value = goavro.Union(avroType(column.kind), value)
+			}
+			record[column.name] = value
+		}
+		records = append(records, record)
+	}
+
+	// every append is written as a block of the file
+	return aw.writer.Append(records)
+}
+
+// open creates the Avro writer with the schema of the collection and the first records
+func (aw *avroWriter) open(objects []map[string]interface{}) error {
+	columns, err := collectionColumns(aw.collection, objects)
+	if err != nil {
+		return err
+	}
+
+	var fields []map[string]interface{}
+	for _, column := range columns {
+		if !avroName.MatchString(column.name) {
+			logging.Warnf("%s column %q isn't a valid Avro name, enable sanitize_columns to write it", aw.collection, column.name)
+			continue
+		}
+		aw.columns = append(aw.columns, column)
+		fields = append(fields, map[string]interface{}{
+			"name":    column.name,
+			"type":    []string{"null", avroType(column.kind)},
+			"default": nil,
+		})
+	}
+	schema, err := json.Marshal(map[string]interface{}{
+		"type":      "record",
+		"name":      sanitizeColumnName(aw.collection),
+		"namespace": "stoplight",
+		"fields":    fields,
+	})
+	if err != nil {
+		return err
+	}
+
+	codec, err := goavro.NewCodec(string(schema))
+	if err != nil {
+		return fmt.Errorf("Error creating %s Avro schema: %v", aw.collection, err)
+	}
+	aw.writer, err = goavro.NewOCFWriter(goavro.OCFConfig{W: aw.w, Codec: codec, CompressionName: goavro.CompressionSnappyLabel})
+	if err != nil {
+		return fmt.Errorf("Error creating %s Avro writer: %v", aw.collection, err)
+	}
+
+	return nil
+}
+
+// Close doesn't have anything to flush, the blocks are written by Write
+func (aw *avroWriter) Close() error {
+	return nil
+}
+
+// avroType returns the Avro type of the column kind, JSON columns are JSON strings
+func avroType(kind string) string {
+	switch kind {
+	case columnNumber:
+		return "double"
+	case columnInteger:
+		return "long"
+	case columnBoolean:
+		return "boolean"
+	default:
+		return "string"
+	}
+}
@@ -0,0 +1,40 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAvroWriterSkipsInvalidNames(t *testing.T) {
+	var buffer bytes.Buffer
+	writer := NewAvroWriter(&buffer, "custom_objects.pets").(*avroWriter)
+	err := writer.Write([]map[string]interface{}{{"id": "p1", "pet name": "Rex", "age": 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, column := range writer.columns {
+		names = append(names, column.name)
+	}
+	if len(names) != 2 || names[0] != "age" || names[1] != "id" {
+		t.Errorf("Avro columns %v, expected age and id", names)
+	}
+}
+
+func TestAvroType(t *testing.T) {
+	expected := map[string]string{
+		columnString:  "string",
+		columnNumber:  "double",
+		columnInteger: "long",
+		columnBoolean: "boolean",
+		columnJson:    "string",
+	}
+	for kind, avro := range expected {
+		if avroType(kind) != avro {
+			t.Errorf("Avro type of %s is %s, expected %s", kind, avroType(kind), avro)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/schema"
+)
+
+const (
+	BackfillQueued  = "queued"
+	BackfillRunning = "running"
+	BackfillDone    = "done"
+	BackfillFailed  = "failed"
+)
+
+// intervalCollections are the collections requested by time interval. The other ones are requested in full
+// and filtered by update (or creation) time when they are backfilled
+var intervalCollections = map[string]bool{
+	FormSubmissionsCollection:     true,
+	SurveySubmissionsCollection:   true,
+	TriggerLinkClicksCollection:   true,
+	InvoicesCollection:            true,
+	InvoiceItemsCollection:        true,
+	EstimatesCollection:           true,
+	PaymentOrdersCollection:       true,
+	PaymentTransactionsCollection: true,
+	FunnelStatsCollection:         true,
+	SocialPostsCollection:         true,
+	ReviewsCollection:             true,
+	CourseEnrollmentsCollection:   true,
+	AppointmentNotesCollection:    true,
+	AuditLogsCollection:           true,
+	MessagesCollection:            true,
+}
+
+// backfillRange is the time range of a backfill, to exclusive. Collections extracted by interval are
+// extracted day by day and aren't filtered
+type backfillRange struct {
+	from       time.Time
+	to         time.Time
+	byInterval bool
+	skipped    int
+}
+
+// filter keeps the objects of collections requested in full which were updated (or created) in the range and
+// counts the skipped ones. Objects without update or creation time (calendars, pipelines, custom fields...)
+// can't be filtered by time: they are kept
+func (br *backfillRange) filter(objects []map[string]interface{}) []map[string]interface{} {
+	kept := objects[:0]
+	for _, object := range objects {
+		t := updatedAt(object)
+		if t.IsZero() {
+			t = createdAt(object)
+		}
+		if t.IsZero() || (!t.Before(br.from) && t.Before(br.to)) {
+			kept = append(kept, object)
+			continue
+		}
+		br.skipped++
+	}
+	return kept
+}
+
+// Backfill re-pulls the records of the collection between the from and to days (inclusive) and loads them,
+// without deletion detection nor hybrid mode versions so the cursors of the regular syncs aren't touched and
+// the records already loaded by the webhooks are loaded again. It returns the number of loaded records
+func (s *Stoplight) Backfill(collection string, from time.Time, to time.Time, load WebhookLoadFunc) (int, error) {
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1)
+	if !from.Before(to) {
+		return 0, errors.New("backfill from must be before to")
+	}
+
+	driver := s.forCollection(collection)
+	if driver == s {
+		// the driver itself mustn't be switched to backfill
+		backfillDriver := *s
+		driver = &backfillDriver
+	}
+
+	var intervals []*base.TimeInterval
+	driver.backfill = &backfillRange{from: from, to: to, byInterval: intervalCollections[collection]}
+	if driver.backfill.byInterval {
+		for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+			intervals = append(intervals, base.NewTimeInterval(schema.DAY, day))
+		}
+	} else {
+		intervals = append(intervals, base.NewTimeInterval(schema.DAY, from))
+	}
+
+	loaded := 0
+	syncRunId := newSyncRunId()
+	for _, location := range driver.locations() {
+		location.backfill = driver.backfill
+		for _, interval := range intervals {
+			objects, err := location.extract(interval)
+			if err != nil {
+				return loaded, err
+			}
+			if len(objects) == 0 {
+				continue
+			}
+
+			location.addLocationIds(objects)
+			addSyncMetadata(objects, time.Now(), syncRunId)
+
+			err = load(collection, objects)
+			if err != nil {
+				return loaded, err
+			}
+			s.publish(collection, objects)
+			loaded += len(objects)
+		}
+	}
+
+	if driver.backfill.skipped > 0 {
+		logging.Infof("[%s] %s backfill skipped %d records updated outside of %s - %s", s.collection.SourceID, collection,
+			driver.backfill.skipped, from.Format(dateLayout), to.AddDate(0, 0, -1).Format(dateLayout))
+	}
+
+	return loaded, nil
+}
+
+// BackfillJob is a backfill of a BackfillQueue
+type BackfillJob struct {
+	Id         string `json:"id"`
+	Collection string `json:"collection"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+	Status     string `json:"status"`
+	Loaded     int    `json:"loaded"`
+	Error      string `json:"error,omitempty"`
+}
+
+// BackfillQueue runs the enqueued backfills one at a time, alongside the regular syncs and the webhooks
+type BackfillQueue struct {
+	driver *Stoplight
+	load   WebhookLoadFunc
+
+	mutex sync.Mutex
+	jobs  []*BackfillJob
+	queue chan *BackfillJob
+	done  chan struct{}
+}
+
+// NewBackfillQueue returns a backfill queue loading the backfilled records with load
+func (s *Stoplight) NewBackfillQueue(load WebhookLoadFunc) *BackfillQueue {
+	queue := &BackfillQueue{
+		driver: s,
+		load:   load,
+		queue:  make(chan *BackfillJob, 100),
+		done:   make(chan struct{}),
+	}
+	go queue.run()
+	return queue
+}
+
+// Enqueue enqueues the backfill of the collection between the from and to days (2006-01-02, inclusive)
+func (bq *BackfillQueue) Enqueue(collection string, from string, to string) (BackfillJob, error) {
+	if collection == "" {
+		return BackfillJob{}, errors.New("backfill collection is required")
+	}
+	for _, day := range []string{from, to} {
+		if _, err := time.Parse(dateLayout, day); err != nil {
+			return BackfillJob{}, fmt.Errorf("backfill day %q is invalid: use %s", day, dateLayout)
+		}
+	}
+
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	job := &BackfillJob{Id: hex.EncodeToString(b), Collection: collection, From: from, To: to, Status: BackfillQueued}
+
+	bq.mutex.Lock()
+	defer bq.mutex.Unlock()
+
+	select {
+	case bq.queue <- job:
+	default:
+		return BackfillJob{}, errors.New("backfill queue is full")
+	}
+	bq.jobs = append(bq.jobs, job)
+	return *job, nil
+}
+
+// Jobs returns a copy of the backfills of the queue, in enqueue order
+func (bq *BackfillQueue) Jobs() []BackfillJob {
+	bq.mutex.Lock()
+	defer bq.mutex.Unlock()
+
+	jobs := make([]BackfillJob, 0, len(bq.jobs))
+	for _, job := range bq.jobs {
+		jobs = append(jobs, *job)
+	}
+	return jobs
+}
+
+// Close stops the queue after the running backfill
+func (bq *BackfillQueue) Close() {
+	close(bq.done)
+}
+
+// run runs the enqueued backfills until the queue is closed
+func (bq *BackfillQueue) run() {
+	for {
+		select {
+		case <-bq.done:
+			return
+		case job := <-bq.queue:
+			bq.setStatus(job, BackfillRunning, 0, nil)
+			from, _ := time.Parse(dateLayout, job.From)
+			to, _ := time.Parse(dateLayout, job.To)
+			loaded, err := bq.driver.Backfill(job.Collection, from, to, bq.load)
+			if err != nil {
+				logging.Warnf("[%s] %s backfill %s - %s failed: %v", bq.driver.collection.SourceID, job.Collection, job.From, job.To, err)
+				bq.setStatus(job, BackfillFailed, loaded, err)
+				continue
+			}
+			bq.setStatus(job, BackfillDone, loaded, nil)
+		}
+	}
+}
+
+// setStatus updates the status of the job
+func (bq *BackfillQueue) setStatus(job *BackfillJob, status string, loaded int, err error) {
+	bq.mutex.Lock()
+	defer bq.mutex.Unlock()
+
+	job.Status = status
+	job.Loaded = loaded
+	if err != nil {
+		job.Error = err.Error()
+	}
+}
+
+// Handler returns the http handler of the backfill API:
+//
+//	GET lists the backfills with their status
+//	POST {"collection": "opportunities", "from": "2023-03-01", "to": "2023-03-31"} enqueues a backfill
+func (bq *BackfillQueue) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		var response interface{}
+		switch r.Method {
+		case http.MethodGet:
+			response = bq.Jobs()
+		case http.MethodPost:
+			request := &BackfillJob{}
+			err := json.NewDecoder(r.Body).Decode(request)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			job, err := bq.Enqueue(request.Collection, request.From, request.To)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusAccepted)
+			response = job
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(response)
+	})
+}
@@ -0,0 +1,34 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import "fmt"
+
+// GetBlogs returns the websites blogs of the location
+func (s *Stoplight) GetBlogs() ([]map[string]interface{}, error) {
+	return s.getAllOffsets(apiURL+"/blogs/site/all?locationId="+s.config.LocationId, "data")
+}
+
+// GetBlogPosts returns the posts metadata (title, urlSlug, publishedAt, author) of every blog with their blog_id
+func (s *Stoplight) GetBlogPosts() ([]map[string]interface{}, error) {
+	blogs, err := s.GetBlogs()
+	if err != nil {
+		return nil, err
+	}
+
+	var posts []map[string]interface{}
+	for _, blog := range blogs {
+		url := fmt.Sprintf("%s/blogs/posts/all?locationId=%s&blogId=%v", apiURL, s.config.LocationId, blog["_id"])
+		blogPosts, err := s.getAllOffsets(url, "blogs")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, post := range blogPosts {
+			post["blog_id"] = blog["_id"]
+		}
+		posts = append(posts, blogPosts...)
+	}
+
+	return posts, nil
+}
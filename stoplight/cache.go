@@ -0,0 +1,104 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// calendarsById returns the calendars of the location by id. Calendars are requested once per driver instance
+func (s *Stoplight) calendarsById() (map[string]map[string]interface{}, error) {
+	if s.calendarsCache != nil {
+		return s.calendarsCache, nil
+	}
+
+	calendars, err := s.GetCalendars()
+	if err != nil {
+		return nil, err
+	}
+
+	s.calendarsCache = make(map[string]map[string]interface{}, len(calendars))
+	for _, calendar := range calendars {
+		s.calendarsCache[fmt.Sprint(calendar["id"])] = calendar
+	}
+
+	return s.calendarsCache, nil
+}
+
+// location returns the location settings. The location is requested once per driver instance
+func (s *Stoplight) location() (map[string]interface{}, error) {
+	if s.locationCache != nil {
+		return s.locationCache, nil
+	}
+
+	body, err := s.get(fmt.Sprintf("%s/locations/%s", apiURL, s.config.LocationId))
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Location map[string]interface{} `json:"location"`
+	}
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	s.locationCache = response.Location
+	if s.locationCache == nil {
+		s.locationCache = map[string]interface{}{}
+	}
+
+	return s.locationCache, nil
+}
+
+// pipelines returns the opportunities pipelines of the location. Pipelines are requested once per driver instance
+func (s *Stoplight) pipelines() ([]Pipeline, error) {
+	if s.pipelinesCache != nil {
+		return s.pipelinesCache, nil
+	}
+
+	objects, err := s.getObjects(apiURL+"/opportunities/pipelines?locationId="+s.config.LocationId, "pipelines")
+	if err != nil {
+		return nil, err
+	}
+
+	pipelines := []Pipeline{}
+	if len(objects) > 0 {
+		err = s.decode(objects, &pipelines)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.pipelinesCache = pipelines
+	return s.pipelinesCache, nil
+}
+
+// usersById returns the users of the location by id. Users are requested once per driver instance
+func (s *Stoplight) usersById() (map[string]User, error) {
+	if s.usersCache != nil {
+		return s.usersCache, nil
+	}
+
+	objects, err := s.getObjects(apiURL+"/users/?locationId="+s.config.LocationId, "users")
+	if err != nil {
+		return nil, err
+	}
+
+	var users []User
+	if len(objects) > 0 {
+		err = s.decode(objects, &users)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.usersCache = make(map[string]User, len(users))
+	for _, user := range users {
+		s.usersCache[user.Id] = user
+	}
+
+	return s.usersCache, nil
+}
@@ -0,0 +1,61 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"time"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+const (
+	changeTypeField = "_change_type"
+
+	ChangeInsert = "insert"
+	ChangeUpdate = "update"
+	ChangeDelete = "delete"
+)
+
+// createdAt returns the creation time of the object or zero time if it isn't known
+func createdAt(object map[string]interface{}) time.Time {
+	for _, field := range []string{"createdAt", "dateAdded"} {
+		value, _ := object[field].(string)
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// inferChangeTypes sets the _change_type of the objects which don't have one yet from their creation time:
+// objects created in the synced interval (or never updated) are inserts, the other ones updates.
+// The change type of objects without creation time is left unknown
+func inferChangeTypes(objects []map[string]interface{}, interval *base.TimeInterval) {
+	var lower time.Time
+	if interval != nil {
+		lower = interval.LowerEndpoint()
+	}
+
+	for _, object := range objects {
+		if _, ok := object[changeTypeField]; ok {
+			continue
+		}
+		created := createdAt(object)
+		if created.IsZero() {
+			continue
+		}
+
+		switch {
+		case !lower.IsZero():
+			if created.Before(lower) {
+				object[changeTypeField] = ChangeUpdate
+			} else {
+				object[changeTypeField] = ChangeInsert
+			}
+		case updatedAt(object).After(created):
+			object[changeTypeField] = ChangeUpdate
+		default:
+			object[changeTypeField] = ChangeInsert
+		}
+	}
+}
@@ -0,0 +1,22 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import "testing"
+
+func TestInferChangeTypes(t *testing.T) {
+	objects := []map[string]interface{}{
+		{"id": "c1", "dateAdded": "2023-03-01T10:00:00Z", "dateUpdated": "2023-03-01T10:00:00Z"},
+		{"id": "c2", "dateAdded": "2023-01-01T10:00:00Z", "dateUpdated": "2023-03-01T10:00:00Z"},
+		{"id": "c3"},
+		{"id": "c4", "dateAdded": "2023-03-01T10:00:00Z", changeTypeField: ChangeDelete},
+	}
+
+	inferChangeTypes(objects, nil)
+	expected := []interface{}{ChangeInsert, ChangeUpdate, nil, ChangeDelete}
+	for i, object := range objects {
+		if object[changeTypeField] != expected[i] {
+			t.Errorf("object %v change type, expected %v", object, expected[i])
+		}
+	}
+}
@@ -0,0 +1,108 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	CoerceString    = "string"
+	CoerceInteger   = "integer"
+	CoerceNumber    = "number"
+	CoerceBoolean   = "boolean"
+	CoerceTimestamp = "timestamp"
+	CoerceDate      = "date"
+
+	CoercionFailureNull = "null"
+	CoercionFailureKeep = "keep"
+	CoercionFailureFail = "fail"
+)
+
+// coercionLayouts are the layouts of the dates and timestamps coerced from strings
+var coercionLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02", "01/02/2006", "01-02-2006"}
+
+// coerce converts the fields of the object configured in coercions for the collection type to their target type.
+// Values which can't be converted are set to null, kept as is or fail the sync depending on coercion_failure
+func (s *Stoplight) coerce(object map[string]interface{}) error {
+	for field, target := range s.config.Coercions[s.collection.Type] {
+		value, ok := object[field]
+		if !ok || value == nil {
+			continue
+		}
+
+		coerced, err := coerceValue(value, target)
+		if err == nil {
+			object[field] = coerced
+			continue
+		}
+
+		switch s.config.CoercionFailure {
+		case CoercionFailureKeep:
+		case CoercionFailureFail:
+			return fmt.Errorf("Error coercing %s field %s of object %v: %v", s.collection.Type, field, object["id"], err)
+		default:
+			object[field] = nil
+		}
+	}
+
+	return nil
+}
+
+// coerceValue converts the value to the target type
+func coerceValue(value interface{}, target string) (interface{}, error) {
+	str := strings.TrimSpace(fmt.Sprint(value))
+	switch target {
+	case CoerceString:
+		return fmt.Sprint(value), nil
+	case CoerceInteger:
+		if f, ok := value.(float64); ok {
+			// fractional values aren't truncated: they follow coercion_failure
+			if f != math.Trunc(f) || f > math.MaxInt64 || f < math.MinInt64 {
+				return nil, fmt.Errorf("%v isn't an integer", f)
+			}
+			return int64(f), nil
+		}
+		return strconv.ParseInt(str, 10, 64)
+	case CoerceNumber:
+		if f, ok := value.(float64); ok {
+			return f, nil
+		}
+		return strconv.ParseFloat(strings.ReplaceAll(str, ",", ""), 64)
+	case CoerceBoolean:
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+		return strconv.ParseBool(strings.ToLower(str))
+	case CoerceTimestamp, CoerceDate:
+		t, err := parseTime(value)
+		if err != nil {
+			return nil, err
+		}
+		if target == CoerceDate {
+			return t.Format(dateLayout), nil
+		}
+		return t.UTC().Format(time.RFC3339), nil
+	default:
+		return nil, fmt.Errorf("unsupported coercion type %s", target)
+	}
+}
+
+// parseTime parses a date or a timestamp string or a unix timestamp in milliseconds
+func parseTime(value interface{}) (time.Time, error) {
+	if ms, ok := value.(float64); ok {
+		return time.UnixMilli(int64(ms)), nil
+	}
+
+	str := strings.TrimSpace(fmt.Sprint(value))
+	for _, layout := range coercionLayouts {
+		if t, err := time.Parse(layout, str); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q isn't a date or a timestamp", str)
+}
@@ -0,0 +1,70 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+func TestCoerceValue(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		target   string
+		expected interface{}
+	}{
+		{42.0, CoerceString, "42"},
+		{"42", CoerceInteger, int64(42)},
+		{42.0, CoerceInteger, int64(42)},
+		{"1,234.5", CoerceNumber, 1234.5},
+		{"TRUE", CoerceBoolean, true},
+		{"2023-03-01 10:00:00", CoerceTimestamp, "2023-03-01T10:00:00Z"},
+		{"03/01/2023", CoerceDate, "2023-03-01"},
+		{1677664800000.0, CoerceTimestamp, "2023-03-01T10:00:00Z"},
+	}
+
+	for _, test := range tests {
+		coerced, err := coerceValue(test.value, test.target)
+		if err != nil {
+			t.Errorf("coercing %v to %s: %v", test.value, test.target, err)
+			continue
+		}
+		if coerced != test.expected {
+			t.Errorf("coerced %v to %s as %v (%T), expected %v", test.value, test.target, coerced, coerced, test.expected)
+		}
+	}
+}
+
+func TestCoerceFractionalInteger(t *testing.T) {
+	for _, value := range []interface{}{12.9, "12.9", -0.5} {
+		if coerced, err := coerceValue(value, CoerceInteger); err == nil {
+			t.Errorf("coerced %v to integer %v, expected an error", value, coerced)
+		}
+	}
+}
+
+func TestCoercionFailure(t *testing.T) {
+	object := func() map[string]interface{} {
+		return map[string]interface{}{"id": "o1", "monetaryValue": "n/a"}
+	}
+	coercions := map[string]map[string]string{OpportunitiesCollection: {"monetaryValue": CoerceNumber}}
+	collection := &base.Collection{SourceID: "source", Type: OpportunitiesCollection}
+
+	for failure, expected := range map[string]interface{}{"": nil, CoercionFailureNull: nil, CoercionFailureKeep: "n/a"} {
+		s := &Stoplight{config: &StoplightConfig{Coercions: coercions, CoercionFailure: failure}, collection: collection}
+		coerced := object()
+		err := s.coerce(coerced)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if coerced["monetaryValue"] != expected {
+			t.Errorf("coercion_failure %q set monetaryValue to %v, expected %v", failure, coerced["monetaryValue"], expected)
+		}
+	}
+
+	s := &Stoplight{config: &StoplightConfig{Coercions: coercions, CoercionFailure: CoercionFailureFail}, collection: collection}
+	if s.coerce(object()) == nil {
+		t.Error("coercion_failure fail didn't fail")
+	}
+}
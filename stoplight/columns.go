@@ -0,0 +1,205 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+const (
+	columnString  = "string"
+	columnNumber  = "number"
+	columnInteger = "integer"
+	columnBoolean = "boolean"
+	columnJson    = "json"
+)
+
+var (
+	propertyKindsMutex sync.Mutex
+	propertyKinds      = map[string]map[string]string{}
+)
+
+// column is a typed column of the files written by the typed record writers (Parquet, Avro)
+type column struct {
+	name string
+	kind string
+}
+
+// collectionColumns returns the columns of the objects of the collection sorted by name: the keys of the objects
+// typed by the collection schema (discovery) when it declares them or inferred from their values otherwise,
+// plus the schema properties missing from the objects. Objects and arrays are JSON columns
+func collectionColumns(collection string, objects []map[string]interface{}) ([]*column, error) {
+	kinds, err := collectionPropertyKinds(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]string{}
+	for name, kind := range kinds {
+		columns[name] = kind
+	}
+	for _, object := range objects {
+		for name, value := range object {
+			if _, ok := columns[name]; ok && columns[name] != "" {
+				continue
+			}
+			columns[name] = valueKind(value)
+		}
+	}
+
+	sorted := make([]*column, 0, len(columns))
+	for name, kind := range columns {
+		if kind == "" {
+			// only null values: the type is unknown
+			kind = columnString
+		}
+		sorted = append(sorted, &column{name: name, kind: kind})
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].name < sorted[j].name })
+
+	return sorted, nil
+}
+
+// valueKind returns the column kind of the value or an empty kind for null
+func valueKind(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case bool:
+		return columnBoolean
+	case int, int32, int64:
+		return columnInteger
+	case float32, float64:
+		return columnNumber
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return columnInteger
+		}
+		return columnNumber
+	case string:
+		return columnString
+	default:
+		return columnJson
+	}
+}
+
+// collectionPropertyKinds returns the column kinds of the properties of the embedded schema of the collection type
+// or nil if the collection doesn't have one
+func collectionPropertyKinds(collectionType string) (map[string]string, error) {
+	propertyKindsMutex.Lock()
+	defer propertyKindsMutex.Unlock()
+
+	if kinds, ok := propertyKinds[collectionType]; ok {
+		return kinds, nil
+	}
+
+	b, err := schemasFS.ReadFile("schemas/" + collectionType + ".json")
+	if err != nil {
+		propertyKinds[collectionType] = nil
+		return nil, nil
+	}
+
+	var schema struct {
+		Properties map[string]struct {
+			Type interface{} `json:"type"`
+		} `json:"properties"`
+	}
+	err = json.Unmarshal(b, &schema)
+	if err != nil {
+		return nil, err
+	}
+
+	kinds := make(map[string]string, len(schema.Properties))
+	for name, property := range schema.Properties {
+		types, ok := property.Type.([]interface{})
+		if !ok {
+			types = []interface{}{property.Type}
+		}
+		for _, t := range types {
+			switch t {
+			case "string", "number", "integer", "boolean":
+				kinds[name] = t.(string)
+			case "object", "array":
+				kinds[name] = columnJson
+			default:
+				continue
+			}
+			break
+		}
+	}
+	propertyKinds[collectionType] = kinds
+
+	return kinds, nil
+}
+
+// columnValue converts the value to the kind of the column. Values which can't be converted are null
+func columnValue(kind string, value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+
+	switch kind {
+	case columnString:
+		if s, ok := value.(string); ok {
+			return s
+		}
+		if valueKind(value) == columnJson {
+			b, _ := json.Marshal(value)
+			return string(b)
+		}
+		return fmt.Sprint(value)
+	case columnNumber, columnInteger:
+		var f float64
+		switch v := value.(type) {
+		case float64:
+			f = v
+		case float32:
+			f = float64(v)
+		case int:
+			f = float64(v)
+		case int32:
+			f = float64(v)
+		case int64:
+			if kind == columnInteger {
+				return v
+			}
+			f = float64(v)
+		case json.Number:
+			var err error
+			if f, err = v.Float64(); err != nil {
+				return nil
+			}
+		case string:
+			var err error
+			if f, err = strconv.ParseFloat(v, 64); err != nil {
+				return nil
+			}
+		default:
+			return nil
+		}
+		if kind == columnInteger {
+			return int64(f)
+		}
+		return f
+	case columnBoolean:
+		switch v := value.(type) {
+		case bool:
+			return v
+		case string:
+			if b, err := strconv.ParseBool(v); err == nil {
+				return b
+			}
+		}
+		return nil
+	default:
+		b, err := json.Marshal(value)
+		if err != nil {
+			return nil
+		}
+		return string(b)
+	}
+}
@@ -0,0 +1,63 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCollectionColumns(t *testing.T) {
+	columns, err := collectionColumns(ContactsCollection, []map[string]interface{}{
+		{"id": "c1", "dnd": nil, "score": 12, "rating": 4.5, "note": nil},
+		{"id": "c2", "dnd": true, "score": json.Number("3")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kinds := map[string]string{}
+	for _, column := range columns {
+		kinds[column.name] = column.kind
+	}
+	expected := map[string]string{
+		// typed by the contacts schema, including the properties missing from the objects
+		"id": columnString, "dnd": columnBoolean, "tags": columnJson, "dndSettings": columnJson, "email": columnString,
+		// inferred from the values
+		"score": columnInteger, "rating": columnNumber, "note": columnString,
+	}
+	for name, kind := range expected {
+		if kinds[name] != kind {
+			t.Errorf("column %s kind %q, expected %q", name, kinds[name], kind)
+		}
+	}
+	for i := 1; i < len(columns); i++ {
+		if columns[i-1].name > columns[i].name {
+			t.Errorf("columns aren't sorted by name: %s before %s", columns[i-1].name, columns[i].name)
+		}
+	}
+}
+
+func TestColumnValue(t *testing.T) {
+	tests := []struct {
+		kind     string
+		value    interface{}
+		expected interface{}
+	}{
+		{columnString, 12.5, "12.5"},
+		{columnString, []interface{}{"a"}, `["a"]`},
+		{columnInteger, json.Number("42"), int64(42)},
+		{columnInteger, "7", int64(7)},
+		{columnNumber, "1.5", 1.5},
+		{columnNumber, "n/a", nil},
+		{columnBoolean, "true", true},
+		{columnBoolean, 1.0, nil},
+		{columnJson, map[string]interface{}{"a": 1.0}, `{"a":1}`},
+		{columnString, nil, nil},
+	}
+	for _, test := range tests {
+		if value := columnValue(test.kind, test.value); value != test.expected {
+			t.Errorf("%s value of %v is %#v, expected %#v", test.kind, test.value, value, test.expected)
+		}
+	}
+}
@@ -6,15 +6,111 @@ package stoplight
 
 import (
 	"errors"
+	"fmt"
+	"time"
+
 	"github.com/jitsucom/jitsu/server/drivers/base"
 )
 
 type StoplightConfig struct {
-	AccessToken  string                 `mapstructure:"access_token" json:"access_token,omitempty" yaml:"access_token,omitempty"`
-	ApiVersion   string                 `mapstructure:"api_version" json:"api_version,omitempty" yaml:"api_version,omitempty"`
-	Calendars    *base.CollectionConfig `mapstructure:"calendars" json:"calendars,omitempty" yaml:"calendars,omitempty"`
-	Contacts     *base.CollectionConfig `mapstructure:"contacts" json:"contacts,omitempty" yaml:"contacts,omitempty"`
-	Opportunities *base.CollectionConfig `mapstructure:"opportunities" json:"opportunities,omitempty" yaml:"opportunities,omitempty"`
+	AccessToken          string                 `mapstructure:"access_token" json:"access_token,omitempty" yaml:"access_token,omitempty"`
+	ApiVersion           string                 `mapstructure:"api_version" json:"api_version,omitempty" yaml:"api_version,omitempty"`
+	LocationId           string                 `mapstructure:"location_id" json:"location_id,omitempty" yaml:"location_id,omitempty"`
+	LocationIds          []string               `mapstructure:"location_ids" json:"location_ids,omitempty" yaml:"location_ids,omitempty"`
+	CompanyId            string                 `mapstructure:"company_id" json:"company_id,omitempty" yaml:"company_id,omitempty"`
+	Calendars            *base.CollectionConfig `mapstructure:"calendars" json:"calendars,omitempty" yaml:"calendars,omitempty"`
+	Contacts             *base.CollectionConfig `mapstructure:"contacts" json:"contacts,omitempty" yaml:"contacts,omitempty"`
+	Opportunities        *base.CollectionConfig `mapstructure:"opportunities" json:"opportunities,omitempty" yaml:"opportunities,omitempty"`
+	CustomFields         *base.CollectionConfig `mapstructure:"custom_fields" json:"custom_fields,omitempty" yaml:"custom_fields,omitempty"`
+	CustomValues         *base.CollectionConfig `mapstructure:"custom_values" json:"custom_values,omitempty" yaml:"custom_values,omitempty"`
+	ContactCustomFields  *base.CollectionConfig `mapstructure:"contact_custom_fields" json:"contact_custom_fields,omitempty" yaml:"contact_custom_fields,omitempty"`
+	ContactTags          *base.CollectionConfig `mapstructure:"contact_tags" json:"contact_tags,omitempty" yaml:"contact_tags,omitempty"`
+	ContactAppointments  *base.CollectionConfig `mapstructure:"contact_appointments" json:"contact_appointments,omitempty" yaml:"contact_appointments,omitempty"`
+	ContactAttributions  *base.CollectionConfig `mapstructure:"contact_attributions" json:"contact_attributions,omitempty" yaml:"contact_attributions,omitempty"`
+	Forms                *base.CollectionConfig `mapstructure:"forms" json:"forms,omitempty" yaml:"forms,omitempty"`
+	FormSubmissions      *base.CollectionConfig `mapstructure:"form_submissions" json:"form_submissions,omitempty" yaml:"form_submissions,omitempty"`
+	Surveys              *base.CollectionConfig `mapstructure:"surveys" json:"surveys,omitempty" yaml:"surveys,omitempty"`
+	SurveySubmissions    *base.CollectionConfig `mapstructure:"survey_submissions" json:"survey_submissions,omitempty" yaml:"survey_submissions,omitempty"`
+	Workflows            *base.CollectionConfig `mapstructure:"workflows" json:"workflows,omitempty" yaml:"workflows,omitempty"`
+	Campaigns            *base.CollectionConfig `mapstructure:"campaigns" json:"campaigns,omitempty" yaml:"campaigns,omitempty"`
+	EmailTemplates       *base.CollectionConfig `mapstructure:"email_templates" json:"email_templates,omitempty" yaml:"email_templates,omitempty"`
+	SmsTemplates         *base.CollectionConfig `mapstructure:"sms_templates" json:"sms_templates,omitempty" yaml:"sms_templates,omitempty"`
+	TriggerLinks         *base.CollectionConfig `mapstructure:"trigger_links" json:"trigger_links,omitempty" yaml:"trigger_links,omitempty"`
+	TriggerLinkClicks    *base.CollectionConfig `mapstructure:"trigger_link_clicks" json:"trigger_link_clicks,omitempty" yaml:"trigger_link_clicks,omitempty"`
+	MediaFiles           *base.CollectionConfig `mapstructure:"media_files" json:"media_files,omitempty" yaml:"media_files,omitempty"`
+	Invoices             *base.CollectionConfig `mapstructure:"invoices" json:"invoices,omitempty" yaml:"invoices,omitempty"`
+	InvoiceItems         *base.CollectionConfig `mapstructure:"invoice_items" json:"invoice_items,omitempty" yaml:"invoice_items,omitempty"`
+	InvoiceTemplates     *base.CollectionConfig `mapstructure:"invoice_templates" json:"invoice_templates,omitempty" yaml:"invoice_templates,omitempty"`
+	Estimates            *base.CollectionConfig `mapstructure:"estimates" json:"estimates,omitempty" yaml:"estimates,omitempty"`
+	PaymentOrders        *base.CollectionConfig `mapstructure:"payment_orders" json:"payment_orders,omitempty" yaml:"payment_orders,omitempty"`
+	PaymentTransactions  *base.CollectionConfig `mapstructure:"payment_transactions" json:"payment_transactions,omitempty" yaml:"payment_transactions,omitempty"`
+	PaymentSubscriptions *base.CollectionConfig `mapstructure:"payment_subscriptions" json:"payment_subscriptions,omitempty" yaml:"payment_subscriptions,omitempty"`
+	Products             *base.CollectionConfig `mapstructure:"products" json:"products,omitempty" yaml:"products,omitempty"`
+	ProductPrices        *base.CollectionConfig `mapstructure:"product_prices" json:"product_prices,omitempty" yaml:"product_prices,omitempty"`
+	Coupons              *base.CollectionConfig `mapstructure:"coupons" json:"coupons,omitempty" yaml:"coupons,omitempty"`
+	Funnels              *base.CollectionConfig `mapstructure:"funnels" json:"funnels,omitempty" yaml:"funnels,omitempty"`
+	FunnelPages          *base.CollectionConfig `mapstructure:"funnel_pages" json:"funnel_pages,omitempty" yaml:"funnel_pages,omitempty"`
+	FunnelStats          *base.CollectionConfig `mapstructure:"funnel_stats" json:"funnel_stats,omitempty" yaml:"funnel_stats,omitempty"`
+	Blogs                *base.CollectionConfig `mapstructure:"blogs" json:"blogs,omitempty" yaml:"blogs,omitempty"`
+	BlogPosts            *base.CollectionConfig `mapstructure:"blog_posts" json:"blog_posts,omitempty" yaml:"blog_posts,omitempty"`
+	SocialAccounts       *base.CollectionConfig `mapstructure:"social_accounts" json:"social_accounts,omitempty" yaml:"social_accounts,omitempty"`
+	SocialPosts          *base.CollectionConfig `mapstructure:"social_posts" json:"social_posts,omitempty" yaml:"social_posts,omitempty"`
+	Reviews              *base.CollectionConfig `mapstructure:"reviews" json:"reviews,omitempty" yaml:"reviews,omitempty"`
+	MembershipProducts   *base.CollectionConfig `mapstructure:"membership_products" json:"membership_products,omitempty" yaml:"membership_products,omitempty"`
+	CourseOffers         *base.CollectionConfig `mapstructure:"course_offers" json:"course_offers,omitempty" yaml:"course_offers,omitempty"`
+	CourseEnrollments    *base.CollectionConfig `mapstructure:"course_enrollments" json:"course_enrollments,omitempty" yaml:"course_enrollments,omitempty"`
+	ObjectSchemas        *base.CollectionConfig `mapstructure:"object_schemas" json:"object_schemas,omitempty" yaml:"object_schemas,omitempty"`
+	Associations         *base.CollectionConfig `mapstructure:"associations" json:"associations,omitempty" yaml:"associations,omitempty"`
+	SaasSubscriptions    *base.CollectionConfig `mapstructure:"saas_subscriptions" json:"saas_subscriptions,omitempty" yaml:"saas_subscriptions,omitempty"`
+	Snapshots            *base.CollectionConfig `mapstructure:"snapshots" json:"snapshots,omitempty" yaml:"snapshots,omitempty"`
+	AppointmentNotes     *base.CollectionConfig `mapstructure:"appointment_notes" json:"appointment_notes,omitempty" yaml:"appointment_notes,omitempty"`
+	ContactDndSettings   *base.CollectionConfig `mapstructure:"contact_dnd_settings" json:"contact_dnd_settings,omitempty" yaml:"contact_dnd_settings,omitempty"`
+	DuplicateContacts    *base.CollectionConfig `mapstructure:"duplicate_contacts" json:"duplicate_contacts,omitempty" yaml:"duplicate_contacts,omitempty"`
+	AuditLogs            *base.CollectionConfig `mapstructure:"audit_logs" json:"audit_logs,omitempty" yaml:"audit_logs,omitempty"`
+	PhoneNumbers         *base.CollectionConfig `mapstructure:"phone_numbers" json:"phone_numbers,omitempty" yaml:"phone_numbers,omitempty"`
+	UrlRedirects         *base.CollectionConfig `mapstructure:"url_redirects" json:"url_redirects,omitempty" yaml:"url_redirects,omitempty"`
+	Messages             *base.CollectionConfig `mapstructure:"messages" json:"messages,omitempty" yaml:"messages,omitempty"`
+
+	PivotCustomFields              bool                              `mapstructure:"pivot_custom_fields" json:"pivot_custom_fields,omitempty" yaml:"pivot_custom_fields,omitempty"`
+	ContactAppointmentsConcurrency int                               `mapstructure:"contact_appointments_concurrency" json:"contact_appointments_concurrency,omitempty" yaml:"contact_appointments_concurrency,omitempty"`
+	BulkExport                     bool                              `mapstructure:"bulk_export" json:"bulk_export,omitempty" yaml:"bulk_export,omitempty"`
+	BulkExportTimeout              string                            `mapstructure:"bulk_export_timeout" json:"bulk_export_timeout,omitempty" yaml:"bulk_export_timeout,omitempty"`
+	Flatten                        bool                              `mapstructure:"flatten" json:"flatten,omitempty" yaml:"flatten,omitempty"`
+	FlattenSeparator               string                            `mapstructure:"flatten_separator" json:"flatten_separator,omitempty" yaml:"flatten_separator,omitempty"`
+	FlattenMaxDepth                int                               `mapstructure:"flatten_max_depth" json:"flatten_max_depth,omitempty" yaml:"flatten_max_depth,omitempty"`
+	ArrayExplosions                []*ArrayExplosion                 `mapstructure:"array_explosions" json:"array_explosions,omitempty" yaml:"array_explosions,omitempty"`
+	SnakeCase                      bool                              `mapstructure:"snake_case" json:"snake_case,omitempty" yaml:"snake_case,omitempty"`
+	SanitizeColumns                bool                              `mapstructure:"sanitize_columns" json:"sanitize_columns,omitempty" yaml:"sanitize_columns,omitempty"`
+	NormalizePhones                bool                              `mapstructure:"normalize_phones" json:"normalize_phones,omitempty" yaml:"normalize_phones,omitempty"`
+	NormalizeEmails                bool                              `mapstructure:"normalize_emails" json:"normalize_emails,omitempty" yaml:"normalize_emails,omitempty"`
+	StripEmailPlusAddressing       bool                              `mapstructure:"strip_email_plus_addressing" json:"strip_email_plus_addressing,omitempty" yaml:"strip_email_plus_addressing,omitempty"`
+	Currency                       string                            `mapstructure:"currency" json:"currency,omitempty" yaml:"currency,omitempty"`
+	MaskingRules                   []*MaskingRule                    `mapstructure:"masking_rules" json:"masking_rules,omitempty" yaml:"masking_rules,omitempty"`
+	MaskingSalt                    string                            `mapstructure:"masking_salt" json:"masking_salt,omitempty" yaml:"masking_salt,omitempty"`
+	DeletionDetection              bool                              `mapstructure:"deletion_detection" json:"deletion_detection,omitempty" yaml:"deletion_detection,omitempty"`
+	DeletionDetectionInterval      string                            `mapstructure:"deletion_detection_interval" json:"deletion_detection_interval,omitempty" yaml:"deletion_detection_interval,omitempty"`
+	StateDir                       string                            `mapstructure:"state_dir" json:"state_dir,omitempty" yaml:"state_dir,omitempty"`
+	SchemaValidation               string                            `mapstructure:"schema_validation" json:"schema_validation,omitempty" yaml:"schema_validation,omitempty"`
+	JsTransforms                   map[string]string                 `mapstructure:"js_transforms" json:"js_transforms,omitempty" yaml:"js_transforms,omitempty"`
+	JsTransformTimeout             string                            `mapstructure:"js_transform_timeout" json:"js_transform_timeout,omitempty" yaml:"js_transform_timeout,omitempty"`
+	JsTransformMaxMemory           int                               `mapstructure:"js_transform_max_memory" json:"js_transform_max_memory,omitempty" yaml:"js_transform_max_memory,omitempty"`
+	Filters                        map[string]string                 `mapstructure:"filters" json:"filters,omitempty" yaml:"filters,omitempty"`
+	FilterErrors                   string                            `mapstructure:"filter_errors" json:"filter_errors,omitempty" yaml:"filter_errors,omitempty"`
+	QualityRules                   []*QualityRule                    `mapstructure:"quality_rules" json:"quality_rules,omitempty" yaml:"quality_rules,omitempty"`
+	Coercions                      map[string]map[string]string      `mapstructure:"coercions" json:"coercions,omitempty" yaml:"coercions,omitempty"`
+	CoercionFailure                string                            `mapstructure:"coercion_failure" json:"coercion_failure,omitempty" yaml:"coercion_failure,omitempty"`
+	Defaults                       map[string]map[string]interface{} `mapstructure:"defaults" json:"defaults,omitempty" yaml:"defaults,omitempty"`
+	EmptyStringsAsNull             bool                              `mapstructure:"empty_strings_as_null" json:"empty_strings_as_null,omitempty" yaml:"empty_strings_as_null,omitempty"`
+	Renames                        map[string]map[string]string      `mapstructure:"renames" json:"renames,omitempty" yaml:"renames,omitempty"`
+	HipaaMode                      bool                              `mapstructure:"hipaa_mode" json:"hipaa_mode,omitempty" yaml:"hipaa_mode,omitempty"`
+	StringIds                      bool                              `mapstructure:"string_ids" json:"string_ids,omitempty" yaml:"string_ids,omitempty"`
+	RawPayload                     bool                              `mapstructure:"raw_payload" json:"raw_payload,omitempty" yaml:"raw_payload,omitempty"`
+	RawPayloadMaxSize              int                               `mapstructure:"raw_payload_max_size" json:"raw_payload_max_size,omitempty" yaml:"raw_payload_max_size,omitempty"`
+	MaxLengths                     map[string]map[string]int         `mapstructure:"max_lengths" json:"max_lengths,omitempty" yaml:"max_lengths,omitempty"`
+	EnrichPipelines                bool                              `mapstructure:"enrich_pipelines" json:"enrich_pipelines,omitempty" yaml:"enrich_pipelines,omitempty"`
+	EnrichUsers                    bool                              `mapstructure:"enrich_users" json:"enrich_users,omitempty" yaml:"enrich_users,omitempty"`
+	Webhooks                       *WebhookConfig                    `mapstructure:"webhooks" json:"webhooks,omitempty" yaml:"webhooks,omitempty"`
+	CursorStore                    *CursorStoreConfig                `mapstructure:"cursor_store" json:"cursor_store,omitempty" yaml:"cursor_store,omitempty"`
 }
 
 //Validate() method validates the StoplightConfig struct and returns an error if any of the fields are invalid
@@ -31,6 +127,123 @@ func (stc *StoplightConfig) Validate() error {
 		return errors.New("Stoplight api_version is required")
 	}
 
+	if stc.LocationId == "" && len(stc.LocationIds) == 0 {
+		return errors.New("Stoplight location_id or location_ids is required")
+	}
+
+	if stc.FlattenMaxDepth < 0 {
+		return errors.New("Stoplight flatten_max_depth must be positive")
+	}
+
+	if stc.RawPayloadMaxSize < 0 {
+		return errors.New("Stoplight raw_payload_max_size must be positive")
+	}
+
+	if timeout, err := stc.bulkExportTimeout(); err != nil || timeout <= 0 {
+		return errors.New("Stoplight bulk_export_timeout must be a positive duration")
+	}
+
+	if interval, err := stc.deletionDetectionInterval(); err != nil || interval <= 0 {
+		return errors.New("Stoplight deletion_detection_interval must be a positive duration")
+	}
+
+	if stc.DeletionDetection && stc.StateDir == "" && stc.CursorStore == nil {
+		return errors.New("Stoplight state_dir or cursor_store is required for deletion_detection")
+	}
+
+	if stc.CursorStore != nil {
+		err := stc.CursorStore.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.SchemaValidation != "" && stc.SchemaValidation != SchemaValidationFail && stc.SchemaValidation != SchemaValidationQuarantine {
+		return fmt.Errorf("Stoplight schema_validation %q is not supported: use fail or quarantine", stc.SchemaValidation)
+	}
+
+	if _, err := stc.jsTransformTimeout(); err != nil {
+		return fmt.Errorf("Stoplight js_transform_timeout is invalid: %v", err)
+	}
+
+	if stc.JsTransformMaxMemory < 0 {
+		return errors.New("Stoplight js_transform_max_memory must be positive")
+	}
+
+	for collection, expression := range stc.Filters {
+		if _, err := compileFilter(expression); err != nil {
+			return fmt.Errorf("Stoplight %s filter is invalid: %v", collection, err)
+		}
+	}
+
+	switch stc.FilterErrors {
+	case "", FilterErrorSkip, FilterErrorFail:
+	default:
+		return fmt.Errorf("Stoplight filter_errors %q is not supported: use skip or fail", stc.FilterErrors)
+	}
+
+	for collection, coercions := range stc.Coercions {
+		for field, target := range coercions {
+			switch target {
+			case CoerceString, CoerceInteger, CoerceNumber, CoerceBoolean, CoerceTimestamp, CoerceDate:
+			default:
+				return fmt.Errorf("Stoplight %s coercion type %q of field %s is not supported", collection, target, field)
+			}
+		}
+	}
+
+	for collection, lengths := range stc.MaxLengths {
+		for field, length := range lengths {
+			if length <= 0 {
+				return fmt.Errorf("Stoplight %s max length of field %s must be positive", collection, field)
+			}
+		}
+	}
+
+	switch stc.CoercionFailure {
+	case "", CoercionFailureNull, CoercionFailureKeep, CoercionFailureFail:
+	default:
+		return fmt.Errorf("Stoplight coercion_failure %q is not supported: use null, keep or fail", stc.CoercionFailure)
+	}
+
+	for _, rule := range stc.QualityRules {
+		err := rule.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, rule := range stc.MaskingRules {
+		err := rule.Validate()
+		if err != nil {
+			return err
+		}
+		if rule.Policy == MaskHash && stc.MaskingSalt == "" {
+			return fmt.Errorf("Stoplight masking_salt is required for the hash policy of field %s: unsalted hashes can be reversed by brute force", rule.Field)
+		}
+	}
+	if stc.HipaaMode && stc.MaskingSalt == "" {
+		return errors.New("Stoplight masking_salt is required for hipaa_mode: unsalted hashes of phones or emails can be reversed by brute force")
+	}
+
+	for _, explosion := range stc.ArrayExplosions {
+		err := explosion.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.Webhooks != nil {
+		err := stc.Webhooks.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.Webhooks != nil && stc.Webhooks.Hybrid && stc.StateDir == "" && stc.CursorStore == nil {
+		return errors.New("Stoplight state_dir or cursor_store is required for webhooks hybrid mode")
+	}
+
 	if stc.Calendars == nil {
 		return errors.New("Stoplight calendars collection is required")
 	}
@@ -58,5 +271,410 @@ func (stc *StoplightConfig) Validate() error {
 		return err
 	}
 
+	if stc.CustomFields != nil {
+		err = stc.CustomFields.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.CustomValues != nil {
+		err = stc.CustomValues.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.ContactCustomFields != nil {
+		err = stc.ContactCustomFields.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.ContactTags != nil {
+		err = stc.ContactTags.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.ContactAppointments != nil {
+		err = stc.ContactAppointments.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.ContactAttributions != nil {
+		err = stc.ContactAttributions.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.Forms != nil {
+		err = stc.Forms.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.FormSubmissions != nil {
+		err = stc.FormSubmissions.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.Surveys != nil {
+		err = stc.Surveys.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.SurveySubmissions != nil {
+		err = stc.SurveySubmissions.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.Workflows != nil {
+		err = stc.Workflows.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.Campaigns != nil {
+		err = stc.Campaigns.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.EmailTemplates != nil {
+		err = stc.EmailTemplates.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.SmsTemplates != nil {
+		err = stc.SmsTemplates.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.TriggerLinks != nil {
+		err = stc.TriggerLinks.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.TriggerLinkClicks != nil {
+		err = stc.TriggerLinkClicks.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.MediaFiles != nil {
+		err = stc.MediaFiles.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.Invoices != nil {
+		err = stc.Invoices.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.InvoiceItems != nil {
+		err = stc.InvoiceItems.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.InvoiceTemplates != nil {
+		err = stc.InvoiceTemplates.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.Estimates != nil {
+		err = stc.Estimates.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.PaymentOrders != nil {
+		err = stc.PaymentOrders.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.PaymentTransactions != nil {
+		err = stc.PaymentTransactions.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.PaymentSubscriptions != nil {
+		err = stc.PaymentSubscriptions.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.Products != nil {
+		err = stc.Products.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.ProductPrices != nil {
+		err = stc.ProductPrices.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.Coupons != nil {
+		err = stc.Coupons.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.Funnels != nil {
+		err = stc.Funnels.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.FunnelPages != nil {
+		err = stc.FunnelPages.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.FunnelStats != nil {
+		err = stc.FunnelStats.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.Blogs != nil {
+		err = stc.Blogs.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.BlogPosts != nil {
+		err = stc.BlogPosts.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.SocialAccounts != nil {
+		err = stc.SocialAccounts.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.SocialPosts != nil {
+		err = stc.SocialPosts.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.Reviews != nil {
+		err = stc.Reviews.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.MembershipProducts != nil {
+		err = stc.MembershipProducts.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.CourseOffers != nil {
+		err = stc.CourseOffers.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.CourseEnrollments != nil {
+		err = stc.CourseEnrollments.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.ObjectSchemas != nil {
+		err = stc.ObjectSchemas.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.Associations != nil {
+		err = stc.Associations.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.SaasSubscriptions != nil {
+		if stc.CompanyId == "" {
+			return errors.New("Stoplight company_id is required for saas_subscriptions collection")
+		}
+
+		err = stc.SaasSubscriptions.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.Snapshots != nil {
+		if stc.CompanyId == "" {
+			return errors.New("Stoplight company_id is required for snapshots collection")
+		}
+
+		err = stc.Snapshots.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.AppointmentNotes != nil {
+		err = stc.AppointmentNotes.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.ContactDndSettings != nil {
+		err = stc.ContactDndSettings.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.DuplicateContacts != nil {
+		err = stc.DuplicateContacts.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.AuditLogs != nil {
+		if stc.CompanyId == "" {
+			return errors.New("Stoplight company_id is required for audit_logs collection")
+		}
+
+		err = stc.AuditLogs.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.PhoneNumbers != nil {
+		err = stc.PhoneNumbers.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.UrlRedirects != nil {
+		err = stc.UrlRedirects.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if stc.Messages != nil {
+		err = stc.Messages.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// flattenSeparator returns the configured separator of flattened columns names or "_" by default
+func (stc *StoplightConfig) flattenSeparator() string {
+	if stc.FlattenSeparator == "" {
+		return defaultFlattenSeparator
+	}
+	return stc.FlattenSeparator
+}
+
+// arrayExplosion returns the array explosion configured for the collection type or nil
+func (stc *StoplightConfig) arrayExplosion(collectionType string) *ArrayExplosion {
+	for _, explosion := range stc.ArrayExplosions {
+		if explosion.Collection == collectionType {
+			return explosion
+		}
+	}
 	return nil
 }
+
+// bulkExportTimeout returns the configured bulk_export_timeout or 1 hour by default
+func (stc *StoplightConfig) bulkExportTimeout() (time.Duration, error) {
+	if stc.BulkExportTimeout == "" {
+		return defaultExportTimeout, nil
+	}
+	return time.ParseDuration(stc.BulkExportTimeout)
+}
+
+// deletionDetectionInterval returns the configured deletion_detection_interval or 24 hours by default
+func (stc *StoplightConfig) deletionDetectionInterval() (time.Duration, error) {
+	if stc.DeletionDetectionInterval == "" {
+		return defaultDeletionDetectionInterval, nil
+	}
+	return time.ParseDuration(stc.DeletionDetectionInterval)
+}
+
+// jsTransformTimeout returns the configured js_transform_timeout, 0 if it isn't configured
+func (stc *StoplightConfig) jsTransformTimeout() (time.Duration, error) {
+	if stc.JsTransformTimeout == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(stc.JsTransformTimeout)
+}
+
+// rawPayloadMaxSize returns the configured raw_payload_max_size or 64KB by default
+func (stc *StoplightConfig) rawPayloadMaxSize() int {
+	if stc.RawPayloadMaxSize == 0 {
+		return defaultRawPayloadMaxSize
+	}
+	return stc.RawPayloadMaxSize
+}
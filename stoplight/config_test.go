@@ -0,0 +1,104 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+// validConfig returns a minimal valid config
+func validConfig() *StoplightConfig {
+	return &StoplightConfig{
+		AccessToken:   "token",
+		ApiVersion:    "2021-07-28",
+		LocationId:    "location1",
+		Calendars:     &base.CollectionConfig{},
+		Contacts:      &base.CollectionConfig{},
+		Opportunities: &base.CollectionConfig{},
+	}
+}
+
+func TestValidateMaskingSalt(t *testing.T) {
+	config := validConfig()
+	config.HipaaMode = true
+	if config.Validate() == nil {
+		t.Error("hipaa_mode without masking_salt is valid")
+	}
+
+	config.MaskingSalt = "salt"
+	if err := config.Validate(); err != nil {
+		t.Errorf("hipaa_mode with masking_salt is invalid: %v", err)
+	}
+}
+
+func TestValidateHashWithoutSalt(t *testing.T) {
+	config := validConfig()
+	config.MaskingRules = []*MaskingRule{{Field: "email", Policy: MaskHash}}
+	if config.Validate() == nil {
+		t.Error("hash masking rule without masking_salt is valid")
+	}
+
+	config.MaskingRules = []*MaskingRule{{Field: "email", Policy: MaskRedact}}
+	if err := config.Validate(); err != nil {
+		t.Errorf("redact masking rule without masking_salt is invalid: %v", err)
+	}
+}
+
+func TestValidateBulkExportTimeout(t *testing.T) {
+	for _, timeout := range []string{"forever", "0s", "-1m"} {
+		config := validConfig()
+		config.BulkExportTimeout = timeout
+		if config.Validate() == nil {
+			t.Errorf("bulk_export_timeout %q is valid", timeout)
+		}
+	}
+
+	config := validConfig()
+	config.BulkExportTimeout = "30m"
+	if err := config.Validate(); err != nil {
+		t.Errorf("bulk_export_timeout 30m is invalid: %v", err)
+	}
+}
+
+func TestValidateFilterErrors(t *testing.T) {
+	config := validConfig()
+	config.FilterErrors = "ignore"
+	if config.Validate() == nil {
+		t.Error("filter_errors ignore is valid")
+	}
+
+	for _, policy := range []string{"", FilterErrorSkip, FilterErrorFail} {
+		config.FilterErrors = policy
+		if err := config.Validate(); err != nil {
+			t.Errorf("filter_errors %q is invalid: %v", policy, err)
+		}
+	}
+}
+
+func TestValidateRawPayloadWithHipaaMode(t *testing.T) {
+	config := validConfig()
+	config.RawPayload = true
+	config.HipaaMode = true
+	config.MaskingSalt = "salt"
+	if err := config.Validate(); err != nil {
+		t.Errorf("raw_payload with hipaa_mode is invalid: %v", err)
+	}
+}
+
+func TestValidateDeletionDetectionInterval(t *testing.T) {
+	for _, interval := range []string{"daily", "0s", "-1h"} {
+		config := validConfig()
+		config.DeletionDetectionInterval = interval
+		if config.Validate() == nil {
+			t.Errorf("deletion_detection_interval %q is valid", interval)
+		}
+	}
+
+	config := validConfig()
+	config.DeletionDetectionInterval = "12h"
+	if err := config.Validate(); err != nil {
+		t.Errorf("deletion_detection_interval 12h is invalid: %v", err)
+	}
+}
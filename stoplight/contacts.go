@@ -0,0 +1,256 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const defaultContactAppointmentsConcurrency = 5
+
+// GetContactTags returns one (contact_id, tag) row per tag of every contact
+func (s *Stoplight) GetContactTags() ([]map[string]interface{}, error) {
+	contacts, err := s.listTypedContacts()
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []map[string]interface{}
+	for _, contact := range contacts {
+		for _, tag := range contact.Tags {
+			rows = append(rows, map[string]interface{}{
+				"contact_id": contact.Id,
+				"tag":        tag,
+			})
+		}
+	}
+
+	return rows, nil
+}
+
+// GetContactAppointments returns the appointments of every contact using the contact appointments endpoint.
+// Contacts are requested by contact_appointments_concurrency parallel workers
+func (s *Stoplight) GetContactAppointments() ([]map[string]interface{}, error) {
+	contacts, err := s.listContacts()
+	if err != nil {
+		return nil, err
+	}
+
+	concurrency := s.config.ContactAppointmentsConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultContactAppointmentsConcurrency
+	}
+
+	ids := make(chan string)
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	var appointments []map[string]interface{}
+	var firstErr error
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range ids {
+				events, err := s.getObjects(fmt.Sprintf("%s/contacts/%s/appointments", apiURL, id), "events")
+
+				mutex.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = fmt.Errorf("Error getting appointments of contact %s: %v", id, err)
+					}
+				} else {
+					appointments = append(appointments, events...)
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	for _, contact := range contacts {
+		mutex.Lock()
+		failed := firstErr != nil
+		mutex.Unlock()
+		if failed {
+			break
+		}
+		ids <- fmt.Sprint(contact["id"])
+	}
+	close(ids)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	err = s.shapeAppointments(appointments)
+	if err != nil {
+		return nil, err
+	}
+
+	return appointments, nil
+}
+
+// shapeAppointments applies the post-fetch steps of the appointments: timezone resolution and calendars and
+// users enrichment
+func (s *Stoplight) shapeAppointments(appointments []map[string]interface{}) error {
+	err := s.resolveTimezones(appointments)
+	if err != nil {
+		return err
+	}
+
+	err = s.addCalendars(appointments)
+	if err != nil {
+		return err
+	}
+
+	if s.config.EnrichUsers {
+		err = s.enrichUsers(appointments, "assignedUserId", "assigned_user")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetContactAttributions returns the first (attributionSource) and last (lastAttributionSource) attribution
+// of every contact as rows with contact_id, attribution type and the attribution fields
+// (campaign, medium, sessionSource, adId, ...)
+func (s *Stoplight) GetContactAttributions() ([]map[string]interface{}, error) {
+	contacts, err := s.listContacts()
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []map[string]interface{}
+	for _, contact := range contacts {
+		for _, attribution := range []struct{ kind, key string }{{"first", "attributionSource"}, {"last", "lastAttributionSource"}} {
+			source, ok := contact[attribution.key].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			row := make(map[string]interface{}, len(source)+2)
+			for k, v := range source {
+				row[k] = v
+			}
+			row["contact_id"] = contact["id"]
+			row["attribution_type"] = attribution.kind
+			rows = append(rows, row)
+		}
+	}
+
+	return rows, nil
+}
+
+// GetContactDndSettings returns one (contact_id, channel, status, message, code) row per DND channel setting
+// (SMS, Email, Call, WhatsApp, ...) of every contact. dnd column is the contact global DND flag
+func (s *Stoplight) GetContactDndSettings() ([]map[string]interface{}, error) {
+	contacts, err := s.listTypedContacts()
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []map[string]interface{}
+	for _, contact := range contacts {
+		channels := make([]string, 0, len(contact.DndSettings))
+		for channel := range contact.DndSettings {
+			channels = append(channels, channel)
+		}
+		sort.Strings(channels)
+
+		for _, channel := range channels {
+			setting := contact.DndSettings[channel]
+			rows = append(rows, map[string]interface{}{
+				"contact_id": contact.Id,
+				"dnd":        contact.Dnd,
+				"channel":    channel,
+				"status":     setting.Status,
+				"message":    setting.Message,
+				"code":       setting.Code,
+			})
+		}
+	}
+
+	return rows, nil
+}
+
+// GetDuplicateContacts runs the duplicate search endpoint by email and phone for every contact and returns
+// candidate duplicate pairs as (contact_id, duplicate_contact_id, matched_on) rows. Each pair is returned once
+func (s *Stoplight) GetDuplicateContacts() ([]map[string]interface{}, error) {
+	contacts, err := s.listTypedContacts()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var rows []map[string]interface{}
+	for _, contact := range contacts {
+		for _, search := range []struct{ field, param, value string }{{"email", "email", contact.Email}, {"phone", "number", contact.Phone}} {
+			if search.value == "" {
+				continue
+			}
+
+			body, err := s.get(fmt.Sprintf("%s/contacts/search/duplicate?locationId=%s&%s=%s", apiURL, s.config.LocationId,
+				search.param, url.QueryEscape(search.value)))
+			if err != nil {
+				return nil, err
+			}
+
+			var response struct {
+				Contact *Contact `json:"contact"`
+			}
+			err = json.Unmarshal(body, &response)
+			if err != nil {
+				return nil, err
+			}
+			if response.Contact == nil {
+				continue
+			}
+
+			if response.Contact.Id == contact.Id {
+				continue
+			}
+
+			pair := []string{contact.Id, response.Contact.Id}
+			sort.Strings(pair)
+			key := strings.Join(pair, ":") + ":" + search.field
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			rows = append(rows, map[string]interface{}{
+				"contact_id":           pair[0],
+				"duplicate_contact_id": pair[1],
+				"matched_on":           search.field,
+			})
+		}
+	}
+
+	return rows, nil
+}
+
+// extractEmailVerification surfaces the email validation data of the contact, when available, as
+// email_verified, email_verified_at, email_verification_status, email_verification_score
+// and email_verification_reason columns
+func extractEmailVerification(contact map[string]interface{}) {
+	if valid, ok := contact["validEmail"]; ok {
+		contact["email_verified"] = valid
+		contact["email_verified_at"] = contact["validEmailDate"]
+	}
+
+	validation, ok := contact["emailValidation"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	contact["email_verification_status"] = validation["status"]
+	contact["email_verification_score"] = validation["score"]
+	contact["email_verification_reason"] = validation["reason"]
+	delete(contact, "emailValidation")
+}
@@ -0,0 +1,225 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+)
+
+const (
+	exportPollInterval   = 10 * time.Second
+	defaultExportTimeout = time.Hour
+)
+
+// errContactsExportUnavailable is returned when the export job fails or doesn't complete in bulk_export_timeout
+var errContactsExportUnavailable = errors.New("Stoplight contacts export is unavailable")
+
+// listContacts returns every contact of the location. When bulk_export is enabled, the initial syncs download
+// the contacts with an async export job, falling back to the paginated list endpoint if the export isn't
+// available. The following syncs use the paginated list endpoint
+func (s *Stoplight) listContacts() ([]map[string]interface{}, error) {
+	if s.config.BulkExport {
+		initial, err := s.initialContactsSync()
+		if err != nil {
+			return nil, err
+		}
+		if initial {
+			contacts, err := s.exportContacts()
+			if err == nil {
+				s.keepRawPayloads(contacts)
+				return contacts, s.markContactsListed()
+			}
+			if !isUnsupported(err) && !errors.Is(err, errContactsExportUnavailable) {
+				return nil, err
+			}
+			logging.Warnf("[%s] contacts export failed, listing the contacts page by page: %v", s.collection.SourceID, err)
+		}
+	}
+
+	contacts, err := s.pageContacts()
+	if err != nil {
+		return nil, err
+	}
+	s.keepRawPayloads(contacts)
+
+	return contacts, s.markContactsListed()
+}
+
+// contactsListedKey is the cursor key set once the collection has listed the contacts of the location
+func (s *Stoplight) contactsListedKey() string {
+	return s.GetCollectionMetaKey() + "_" + s.config.LocationId + "_contacts_listed"
+}
+
+// initialContactsSync returns true for backfills and the syncs of a collection which hasn't listed the contacts
+// yet. Without state_dir or cursor_store the first sync can't be told apart: only backfills are initial
+func (s *Stoplight) initialContactsSync() (bool, error) {
+	if s.backfill != nil {
+		return true, nil
+	}
+	if s.config.StateDir == "" && s.config.CursorStore == nil {
+		return false, nil
+	}
+
+	cursors, err := s.cursors()
+	if err != nil {
+		return false, err
+	}
+	b, err := cursors.Get(s.contactsListedKey())
+	if err != nil {
+		return false, err
+	}
+	return b == nil, nil
+}
+
+// markContactsListed records that the collection has listed the contacts. Backfills don't touch the cursors
+// of the regular syncs
+func (s *Stoplight) markContactsListed() error {
+	if !s.config.BulkExport || s.backfill != nil || (s.config.StateDir == "" && s.config.CursorStore == nil) {
+		return nil
+	}
+
+	cursors, err := s.cursors()
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	return cursors.Set(s.contactsListedKey(), b)
+}
+
+// listTypedContacts returns every contact of the location decoded as Contact
+func (s *Stoplight) listTypedContacts() ([]Contact, error) {
+	objects, err := s.listContacts()
+	if err != nil {
+		return nil, err
+	}
+
+	var contacts []Contact
+	err = s.decode(objects, &contacts)
+	if err != nil {
+		return nil, err
+	}
+
+	return contacts, nil
+}
+
+// pageContacts returns every contact of the location using the list endpoint paginated with startAfterId cursor
+func (s *Stoplight) pageContacts() ([]map[string]interface{}, error) {
+	var contacts []map[string]interface{}
+	query := url.Values{"locationId": {s.config.LocationId}, "limit": {fmt.Sprint(pageSize)}}
+	for {
+		body, err := s.get(apiURL + "/contacts/?" + query.Encode())
+		if err != nil {
+			return nil, err
+		}
+
+		var response struct {
+			Contacts []map[string]interface{} `json:"contacts"`
+			Meta     struct {
+				StartAfterId string      `json:"startAfterId"`
+				StartAfter   json.Number `json:"startAfter"`
+			} `json:"meta"`
+		}
+		err = json.Unmarshal(body, &response)
+		if err != nil {
+			return nil, err
+		}
+		contacts = append(contacts, response.Contacts...)
+
+		if len(response.Contacts) < pageSize || response.Meta.StartAfterId == "" {
+			return contacts, nil
+		}
+		query.Set("startAfterId", response.Meta.StartAfterId)
+		query.Set("startAfter", response.Meta.StartAfter.String())
+	}
+}
+
+// exportContacts starts a contacts export job, waits for its completion and downloads the exported
+// newline delimited JSON file
+func (s *Stoplight) exportContacts() ([]map[string]interface{}, error) {
+	body, err := s.post(apiURL+"/contacts/export", map[string]interface{}{"locationId": s.config.LocationId})
+	if err != nil {
+		return nil, err
+	}
+
+	var job struct {
+		Id     string `json:"id"`
+		Status string `json:"status"`
+		Url    string `json:"url"`
+	}
+	err = json.Unmarshal(body, &job)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout, err := s.config.bulkExportTimeout()
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.After(timeout)
+	for job.Status != "completed" {
+		if job.Status == "failed" {
+			return nil, fmt.Errorf("%w: export %s failed", errContactsExportUnavailable, job.Id)
+		}
+
+		select {
+		case <-s.ctx.Done():
+			return nil, s.ctx.Err()
+		case <-deadline:
+			return nil, fmt.Errorf("%w: export %s didn't complete in %s", errContactsExportUnavailable, job.Id, timeout)
+		case <-time.After(exportPollInterval):
+		}
+
+		body, err = s.get(fmt.Sprintf("%s/contacts/export/%s?locationId=%s", apiURL, job.Id, s.config.LocationId))
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal(body, &job)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The export file is served from a signed url which doesn't accept the API authorization header
+	req, err := http.NewRequestWithContext(s.ctx, "GET", job.Url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &apiError{StatusCode: resp.StatusCode}
+	}
+
+	var contacts []map[string]interface{}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		contact := map[string]interface{}{}
+		err = json.Unmarshal(line, &contact)
+		if err != nil {
+			return nil, err
+		}
+		contacts = append(contacts, contact)
+	}
+
+	return contacts, scanner.Err()
+}
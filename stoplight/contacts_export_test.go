@@ -0,0 +1,100 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+type roundTripFunc func(req *http.Request) *http.Response
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req), nil
+}
+
+// fakeApi answers the requests with the JSON responses of their path, and 404 for other paths
+func fakeApi(responses map[string]string) *http.Client {
+	return &http.Client{Transport: roundTripFunc(func(req *http.Request) *http.Response {
+		body, ok := responses[req.URL.Path]
+		status := http.StatusOK
+		if !ok {
+			status, body = http.StatusNotFound, `{}`
+		}
+		return &http.Response{StatusCode: status, Body: ioutil.NopCloser(strings.NewReader(body)), Header: http.Header{}}
+	})}
+}
+
+// contactsApi answers the contacts export with the status and the contacts list with one contact
+func contactsApi(exportStatus int, exports *int) *http.Client {
+	return &http.Client{Transport: roundTripFunc(func(req *http.Request) *http.Response {
+		status, body := http.StatusOK, `{"contacts":[{"id":"c1"}]}`
+		if strings.HasPrefix(req.URL.Path, "/contacts/export") {
+			*exports++
+			status, body = exportStatus, `{}`
+		}
+		return &http.Response{StatusCode: status, Body: ioutil.NopCloser(strings.NewReader(body)), Header: http.Header{}}
+	})}
+}
+
+func TestContactsExportFallsBackToPagination(t *testing.T) {
+	exports := 0
+	s := &Stoplight{
+		client:     contactsApi(http.StatusForbidden, &exports),
+		ctx:        context.Background(),
+		config:     &StoplightConfig{LocationId: "location1", BulkExport: true},
+		collection: &base.Collection{SourceID: "source", Name: "contacts", Type: ContactsCollection},
+		backfill:   &backfillRange{},
+	}
+
+	contacts, err := s.listContacts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exports != 1 || len(contacts) != 1 {
+		t.Errorf("%d exports and %d contacts, expected the export to fall back to the contacts list", exports, len(contacts))
+	}
+}
+
+func TestContactsExportOnlyOnInitialSync(t *testing.T) {
+	exports := 0
+	s := &Stoplight{
+		client:     contactsApi(http.StatusNotFound, &exports),
+		ctx:        context.Background(),
+		config:     &StoplightConfig{LocationId: "location1", BulkExport: true, StateDir: t.TempDir()},
+		collection: &base.Collection{SourceID: "source", Name: "contacts", Type: ContactsCollection},
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := s.listContacts()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if exports != 1 {
+		t.Errorf("%d exports, expected only the first sync to export the contacts", exports)
+	}
+}
+
+func TestContactsExportWithoutStateOnlyOnBackfills(t *testing.T) {
+	exports := 0
+	s := &Stoplight{
+		client:     contactsApi(http.StatusNotFound, &exports),
+		ctx:        context.Background(),
+		config:     &StoplightConfig{LocationId: "location1", BulkExport: true},
+		collection: &base.Collection{SourceID: "source", Name: "contacts", Type: ContactsCollection},
+	}
+
+	_, err := s.listContacts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exports != 0 {
+		t.Errorf("%d exports, expected the regular syncs without state to list the contacts", exports)
+	}
+}
@@ -0,0 +1,34 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+func TestGetContactTags(t *testing.T) {
+	s := &Stoplight{
+		client: fakeApi(map[string]string{
+			"/contacts/": `{"contacts":[{"id":"c1","tags":["lead","vip"]},{"id":"c2"}]}`,
+		}),
+		ctx:        context.Background(),
+		config:     &StoplightConfig{LocationId: "location1"},
+		collection: &base.Collection{SourceID: "source", Name: "contact_tags", Type: ContactTagsCollection},
+	}
+
+	rows, err := s.GetContactTags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []map[string]interface{}{
+		{"contact_id": "c1", "tag": "lead"},
+		{"contact_id": "c1", "tag": "vip"},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("GetContactTags() = %v, expected %v", rows, expected)
+	}
+}
@@ -0,0 +1,215 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	CursorStoreFile = "file"
+	CursorStoreSql  = "sql"
+
+	defaultCursorsTable = "stoplight_cursors"
+)
+
+var sqlIdentifierRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// CursorStore persists the cursor state of the collections (deletion detection seen ids, hybrid mode versions...)
+// outside of the process memory, so it survives restarts and is shared by polling and webhooks
+type CursorStore interface {
+	// Get returns the cursor of the key or nil if it isn't set
+	Get(key string) ([]byte, error)
+	// Set replaces the cursor of the key
+	Set(key string, value []byte) error
+}
+
+// CursorStoreConfig is the configuration of the cursors persistence: files of a directory (file) or rows
+// of a table of a database/sql database (sql). The sql driver must be registered by the application
+type CursorStoreConfig struct {
+	Type   string `mapstructure:"type" json:"type,omitempty" yaml:"type,omitempty"`
+	Dir    string `mapstructure:"dir" json:"dir,omitempty" yaml:"dir,omitempty"`
+	Driver string `mapstructure:"driver" json:"driver,omitempty" yaml:"driver,omitempty"`
+	Dsn    string `mapstructure:"dsn" json:"dsn,omitempty" yaml:"dsn,omitempty"`
+	Table  string `mapstructure:"table" json:"table,omitempty" yaml:"table,omitempty"`
+}
+
+// Validate returns an error if the cursor store configuration is invalid
+func (cc *CursorStoreConfig) Validate() error {
+	switch cc.Type {
+	case CursorStoreFile:
+		if cc.Dir == "" {
+			return errors.New("Stoplight cursor_store dir is required for file cursor store")
+		}
+	case CursorStoreSql:
+		if cc.Driver == "" || cc.Dsn == "" {
+			return errors.New("Stoplight cursor_store driver and dsn are required for sql cursor store")
+		}
+		if cc.Table != "" && !sqlIdentifierRegex.MatchString(cc.Table) {
+			return fmt.Errorf("Stoplight cursor_store table %q is invalid", cc.Table)
+		}
+	default:
+		return fmt.Errorf("Stoplight cursor_store type %q is not supported: use file or sql", cc.Type)
+	}
+
+	return nil
+}
+
+var (
+	cursorStoresMutex sync.Mutex
+	cursorStores      = map[string]CursorStore{}
+)
+
+// cursors returns the cursor store of the driver: the configured cursor_store or files of state_dir.
+// Stores are opened once and shared by the driver instances
+func (s *Stoplight) cursors() (CursorStore, error) {
+	config := s.config.CursorStore
+	if config == nil {
+		config = &CursorStoreConfig{Type: CursorStoreFile, Dir: s.config.StateDir}
+	}
+
+	key := strings.Join([]string{config.Type, config.Dir, config.Driver, config.Dsn, config.Table}, "|")
+	cursorStoresMutex.Lock()
+	defer cursorStoresMutex.Unlock()
+
+	if store, ok := cursorStores[key]; ok {
+		return store, nil
+	}
+
+	var store CursorStore
+	switch config.Type {
+	case CursorStoreFile:
+		store = NewFileCursorStore(config.Dir)
+	case CursorStoreSql:
+		db, err := sql.Open(config.Driver, config.Dsn)
+		if err != nil {
+			return nil, err
+		}
+		table := config.Table
+		if table == "" {
+			table = defaultCursorsTable
+		}
+		store, err = NewSqlCursorStore(db, config.Driver, table)
+		if err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("cursor store type %q is not supported", config.Type)
+	}
+
+	cursorStores[key] = store
+	return store, nil
+}
+
+// fileCursorStore stores the cursors in <key>.json files of a directory
+type fileCursorStore struct {
+	dir string
+}
+
+// NewFileCursorStore returns a cursor store persisting the cursors in files of the directory
+func NewFileCursorStore(dir string) CursorStore {
+	return &fileCursorStore{dir: dir}
+}
+
+func (fs *fileCursorStore) Get(key string) ([]byte, error) {
+	b, err := ioutil.ReadFile(fs.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return b, err
+}
+
+func (fs *fileCursorStore) Set(key string, value []byte) error {
+	// written to a temporary file first so a crash never leaves a truncated cursor
+	path := fs.path(key)
+	err := ioutil.WriteFile(path+".tmp", value, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func (fs *fileCursorStore) path(key string) string {
+	return filepath.Join(fs.dir, filepath.Base(key)+".json")
+}
+
+// sqlCursorStore stores the cursors in a (cursor_key, cursor_value, updated_at) table
+type sqlCursorStore struct {
+	db       *sql.DB
+	table    string
+	postgres bool
+}
+
+// NewSqlCursorStore returns a cursor store persisting the cursors in the table of the database, created
+// if it doesn't exist. driver is the database/sql driver name, used for the placeholders syntax
+func NewSqlCursorStore(db *sql.DB, driver string, table string) (CursorStore, error) {
+	store := &sqlCursorStore{
+		db:       db,
+		table:    table,
+		postgres: driver == "postgres" || driver == "pgx",
+	}
+
+	_, err := db.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (cursor_key VARCHAR(255) PRIMARY KEY, cursor_value TEXT, updated_at TIMESTAMP)", table))
+	if err != nil {
+		return nil, fmt.Errorf("Error creating cursors table %s: %v", table, err)
+	}
+
+	return store, nil
+}
+
+func (ss *sqlCursorStore) Get(key string) ([]byte, error) {
+	var value string
+	err := ss.db.QueryRow(ss.query("SELECT cursor_value FROM %s WHERE cursor_key = ?"), key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []byte(value), nil
+}
+
+func (ss *sqlCursorStore) Set(key string, value []byte) error {
+	now := time.Now().UTC()
+	// update then insert: upserts aren't portable across databases
+	result, err := ss.db.Exec(ss.query("UPDATE %s SET cursor_value = ?, updated_at = ? WHERE cursor_key = ?"), string(value), now, key)
+	if err != nil {
+		return err
+	}
+	if updated, err := result.RowsAffected(); err == nil && updated > 0 {
+		return nil
+	}
+
+	_, err = ss.db.Exec(ss.query("INSERT INTO %s (cursor_key, cursor_value, updated_at) VALUES (?, ?, ?)"), key, string(value), now)
+	return err
+}
+
+// query returns the query on the cursors table with the placeholders of the database
+func (ss *sqlCursorStore) query(format string) string {
+	query := fmt.Sprintf(format, ss.table)
+	if !ss.postgres {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
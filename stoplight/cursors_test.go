@@ -0,0 +1,58 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"testing"
+)
+
+func TestFileCursorStore(t *testing.T) {
+	store := NewFileCursorStore(t.TempDir())
+
+	value, err := store.Get("source_contacts")
+	if err != nil || value != nil {
+		t.Fatalf("unset cursor: %q, %v", value, err)
+	}
+
+	for _, cursor := range []string{`{"version":1}`, `{"version":2}`} {
+		if err := store.Set("source_contacts", []byte(cursor)); err != nil {
+			t.Fatal(err)
+		}
+		value, err = store.Get("source_contacts")
+		if err != nil || string(value) != cursor {
+			t.Errorf("cursor %q, %v, expected %q", value, err, cursor)
+		}
+	}
+
+	// keys can't escape the directory
+	if err := store.Set("../escaped", []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	value, err = store.Get("escaped")
+	if err != nil || string(value) != "{}" {
+		t.Errorf("cursor %q, %v, expected the escaped key in the directory", value, err)
+	}
+}
+
+func TestCursorStoreConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config CursorStoreConfig
+		valid  bool
+	}{
+		{"file", CursorStoreConfig{Type: CursorStoreFile, Dir: "/tmp"}, true},
+		{"file without dir", CursorStoreConfig{Type: CursorStoreFile}, false},
+		{"sql", CursorStoreConfig{Type: CursorStoreSql, Driver: "postgres", Dsn: "dsn", Table: "public.cursors"}, true},
+		{"sql without dsn", CursorStoreConfig{Type: CursorStoreSql, Driver: "postgres"}, false},
+		{"sql invalid table", CursorStoreConfig{Type: CursorStoreSql, Driver: "postgres", Dsn: "dsn", Table: "cursors; DROP TABLE x"}, false},
+		{"unknown type", CursorStoreConfig{Type: "redis"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err == nil) != tt.valid {
+				t.Errorf("Validate() = %v, valid %v", err, tt.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import "fmt"
+
+const customFieldPrefix = "cf_"
+
+// GetContactCustomFields returns one (contact_id, field_id, value) row per custom field value of every contact
+func (s *Stoplight) GetContactCustomFields() ([]map[string]interface{}, error) {
+	contacts, err := s.listContacts()
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []map[string]interface{}
+	for _, contact := range contacts {
+		for _, field := range customFieldValues(contact) {
+			rows = append(rows, map[string]interface{}{
+				"contact_id": contact["id"],
+				"field_id":   field["id"],
+				"value":      field["value"],
+			})
+		}
+	}
+
+	return rows, nil
+}
+
+// pivotCustomFields adds every custom field value of the objects as a column keyed by the field name
+// taken from the custom field definitions. Values of unknown fields are keyed by their id. Names of contact
+// fields (email, id, dateUpdated...) are prefixed with cf_ so custom fields never overwrite them. In hipaa_mode,
+// the values are masked by the data type of the field unless a masking rule is configured for the column
+func (s *Stoplight) pivotCustomFields(objects []map[string]interface{}) error {
+	definitions, err := s.GetCustomFields()
+	if err != nil {
+		return err
+	}
+	native, err := collectionProperties(ContactsCollection)
+	if err != nil {
+		return err
+	}
+
+	names := make(map[string]string, len(definitions))
+	policies := make(map[string]string, len(definitions))
+	for _, definition := range definitions {
+		id := fmt.Sprint(definition["id"])
+		names[id] = fmt.Sprint(definition["name"])
+		dataType, _ := definition["dataType"].(string)
+		policies[id] = hipaaCustomFieldPolicy(dataType)
+	}
+
+	for _, object := range objects {
+		fields := customFieldValues(object)
+		delete(object, "customFields")
+
+		pivoted := make(map[string]bool, len(fields))
+		for _, field := range fields {
+			id := fmt.Sprint(field["id"])
+			name, ok := names[id]
+			if !ok {
+				name = id
+				policies[id] = hipaaCustomFieldPolicy("")
+			}
+			// pivoted fields don't collide with the native fields of the schema nor of the object
+			if _, exists := object[name]; native[name] || (exists && !pivoted[name]) {
+				name = customFieldPrefix + name
+			}
+			pivoted[name] = true
+			object[name] = field["value"]
+			if s.config.HipaaMode && policies[id] != "" && !s.config.maskingConfigured(s.collection.Type, name) {
+				maskKey(object, name, policies[id], s.config.MaskingSalt)
+			}
+		}
+	}
+
+	return nil
+}
+
+// customFieldValues returns the customFields array of the object. HighLevel returns either value or field_value
+// depending on the endpoint, both are returned as value
+func customFieldValues(object map[string]interface{}) []map[string]interface{} {
+	fields, ok := object["customFields"].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	values := make([]map[string]interface{}, 0, len(fields))
+	for _, f := range fields {
+		field, ok := f.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		value, ok := field["value"]
+		if !ok {
+			value = field["field_value"]
+		}
+		values = append(values, map[string]interface{}{"id": field["id"], "value": value})
+	}
+
+	return values
+}
@@ -0,0 +1,129 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+	"github.com/jitsucom/jitsu/server/jsonutils"
+)
+
+const (
+	// customObjectsPrefix is the prefix of HighLevel custom object keys (e.g. custom_objects.pets). Every collection
+	// with a type starting with it is synced from the records of the custom object with the same key
+	customObjectsPrefix = "custom_objects."
+
+	// CustomObjectsCollection is expanded by ListCollections into a collection per custom object of the location
+	CustomObjectsCollection = "custom_objects"
+)
+
+// customObjectTable returns the table name of a custom object key: the "." of the key isn't valid in most
+// destinations table names (custom_objects.pets is loaded into custom_objects_pets)
+func customObjectTable(key string) string {
+	return sanitizeColumnName(strings.ToLower(key))
+}
+
+// DiscoverCustomObjects returns a collection per custom object defined for the location of the source: its type is
+// the custom object key and its name and table the key sanitized into a table name
+func DiscoverCustomObjects(sourceConfig *base.SourceConfig) ([]*base.Collection, error) {
+	config := &StoplightConfig{}
+	err := jsonutils.UnmarshalConfig(sourceConfig.Config, config)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Stoplight{client: &http.Client{}, ctx: context.Background(), config: config}
+	schemas, err := s.getObjects(apiURL+"/objects/?locationId="+config.LocationId, "objects")
+	if err != nil {
+		return nil, err
+	}
+
+	var collections []*base.Collection
+	for _, schema := range schemas {
+		key := fmt.Sprint(schema["key"])
+		if strings.HasPrefix(key, customObjectsPrefix) {
+			collections = append(collections, customObjectCollection(sourceConfig.SourceID, key))
+		}
+	}
+
+	return collections, nil
+}
+
+// customObjectCollection returns the collection of the custom object key
+func customObjectCollection(sourceId string, key string) *base.Collection {
+	table := customObjectTable(key)
+	return &base.Collection{SourceID: sourceId, Name: table, Type: key, TableName: table}
+}
+
+// ListCollections returns the collections of the collection types: custom_objects is expanded into a collection
+// per custom object discovered for the location and custom object keys get a sanitized name and table
+func ListCollections(sourceConfig *base.SourceConfig, collectionTypes []string) ([]*base.Collection, error) {
+	var collections []*base.Collection
+	for _, collectionType := range collectionTypes {
+		switch {
+		case collectionType == CustomObjectsCollection:
+			customObjects, err := DiscoverCustomObjects(sourceConfig)
+			if err != nil {
+				return nil, fmt.Errorf("Error discovering custom objects: %v", err)
+			}
+			collections = append(collections, customObjects...)
+		case strings.HasPrefix(collectionType, customObjectsPrefix):
+			collections = append(collections, customObjectCollection(sourceConfig.SourceID, collectionType))
+		default:
+			collections = append(collections, &base.Collection{SourceID: sourceConfig.SourceID, Name: collectionType, Type: collectionType})
+		}
+	}
+
+	return collections, nil
+}
+
+// GetCustomObjectRecords returns the records of the custom object with the schema key
+func (s *Stoplight) GetCustomObjectRecords(key string) ([]map[string]interface{}, error) {
+	url := fmt.Sprintf("%s/objects/%s/records/search", apiURL, key)
+
+	var records []map[string]interface{}
+	for page := 1; ; page++ {
+		body, err := s.post(url, map[string]interface{}{
+			"locationId": s.config.LocationId,
+			"page":       page,
+			"pageLimit":  pageSize,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		pageRecords, err := parseObjects(body, "records")
+		if err != nil {
+			return nil, err
+		}
+		s.keepRawPayloads(pageRecords)
+		records = append(records, pageRecords...)
+
+		if len(pageRecords) < pageSize {
+			return records, nil
+		}
+	}
+}
+
+// GetObjectSchemas returns the object schema definitions (key, labels, fields with their data types) of the location,
+// including standard objects (contact, opportunity, business) and custom objects
+func (s *Stoplight) GetObjectSchemas() ([]map[string]interface{}, error) {
+	schemas, err := s.getObjects(apiURL+"/objects/?locationId="+s.config.LocationId, "objects")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, schema := range schemas {
+		fields, err := s.getObjects(fmt.Sprintf("%s/objects/%v?locationId=%s&fetchProperties=true", apiURL, schema["key"], s.config.LocationId), "fields")
+		if err != nil {
+			return nil, err
+		}
+		schema["fields"] = fields
+	}
+
+	return schemas, nil
+}
@@ -0,0 +1,35 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+func TestListCollections(t *testing.T) {
+	sourceConfig := &base.SourceConfig{SourceID: "source", Config: map[string]interface{}{"location_id": "location1"}}
+
+	collections, err := ListCollections(sourceConfig, []string{ContactsCollection, "custom_objects.pets"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(collections) != 2 {
+		t.Fatalf("listed %d collections, expected 2", len(collections))
+	}
+	if collections[0].Name != ContactsCollection || collections[0].Type != ContactsCollection {
+		t.Errorf("contacts collection %+v", collections[0])
+	}
+	pets := collections[1]
+	if pets.Name != "custom_objects_pets" || pets.Type != "custom_objects.pets" || pets.TableName != "custom_objects_pets" {
+		t.Errorf("custom object collection %+v, expected the custom_objects_pets table", pets)
+	}
+}
+
+func TestCustomObjectTable(t *testing.T) {
+	s := &Stoplight{collection: &base.Collection{SourceID: "source", Name: "pets", Type: "custom_objects.pets", TableName: "custom_objects.pets"}}
+	if table := s.GetCollectionTable(); table != "custom_objects_pets" {
+		t.Errorf("table %q, expected custom_objects_pets", table)
+	}
+}
@@ -0,0 +1,49 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"fmt"
+	"time"
+)
+
+// dedupe removes the objects returned more than once (e.g. on two pages when records are updated during
+// the pagination). The most recently updated (updatedAt or dateUpdated) object is kept, or the last one returned
+// when the update times are equal or unknown. Objects are keyed by their surrogate key, so surrogate keys must be
+// added first, or their id. Objects without key are kept as is
+func dedupe(objects []map[string]interface{}) []map[string]interface{} {
+	positions := make(map[string]int, len(objects))
+	deduped := make([]map[string]interface{}, 0, len(objects))
+	for _, object := range objects {
+		rawId := recordKey(object)
+		if rawId == nil {
+			deduped = append(deduped, object)
+			continue
+		}
+
+		id := fmt.Sprint(rawId)
+		position, seen := positions[id]
+		if !seen {
+			positions[id] = len(deduped)
+			deduped = append(deduped, object)
+			continue
+		}
+
+		if !updatedAt(object).Before(updatedAt(deduped[position])) {
+			deduped[position] = object
+		}
+	}
+
+	return deduped
+}
+
+// updatedAt returns the update time of the object or zero time if it isn't known
+func updatedAt(object map[string]interface{}) time.Time {
+	for _, field := range []string{"updatedAt", "dateUpdated"} {
+		value, _ := object[field].(string)
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,45 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+func TestDedupe(t *testing.T) {
+	objects := dedupe([]map[string]interface{}{
+		{"id": "c1", "updatedAt": "2023-03-01T11:00:00Z"},
+		{"_id": "p1", "name": "first"},
+		{"id": "c1", "updatedAt": "2023-03-01T10:00:00Z"},
+		{"_id": "p1", "name": "second"},
+		{"name": "without id"},
+		{"name": "without id"},
+	})
+
+	if len(objects) != 4 {
+		t.Fatalf("deduped %d objects, expected 4: %v", len(objects), objects)
+	}
+	if objects[0]["updatedAt"] != "2023-03-01T11:00:00Z" {
+		t.Errorf("kept c1 %v, expected the most recent", objects[0])
+	}
+	if objects[1]["name"] != "second" {
+		t.Errorf("kept p1 %v, expected the last returned", objects[1])
+	}
+}
+
+func TestDedupeCourseOffers(t *testing.T) {
+	s := &Stoplight{config: &StoplightConfig{}, collection: &base.Collection{SourceID: "source", Type: CourseOffersCollection}}
+	objects := []map[string]interface{}{
+		{"id": "offer1", "product_id": "p1"},
+		{"id": "offer1", "product_id": "p2"},
+		{"id": "offer1", "product_id": "p1"},
+	}
+
+	s.addSurrogateKeys(objects)
+	objects = dedupe(objects)
+	if len(objects) != 2 || objects[0]["product_id"] != "p1" || objects[1]["product_id"] != "p2" {
+		t.Errorf("deduped course offers %v, expected a row per product", objects)
+	}
+}
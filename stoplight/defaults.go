@@ -0,0 +1,23 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import "strings"
+
+// emptyStringsToNull replaces the empty (or blank) string fields of the object with null
+func emptyStringsToNull(object map[string]interface{}) {
+	for field, value := range object {
+		if str, ok := value.(string); ok && len(strings.TrimSpace(str)) == 0 {
+			object[field] = nil
+		}
+	}
+}
+
+// applyDefaults sets the defaults configured for the collection type to the missing or null fields of the object
+func (s *Stoplight) applyDefaults(object map[string]interface{}) {
+	for field, value := range s.config.Defaults[s.collection.Type] {
+		if current, ok := object[field]; !ok || current == nil {
+			object[field] = value
+		}
+	}
+}
@@ -0,0 +1,27 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+func TestApplyDefaults(t *testing.T) {
+	s := &Stoplight{
+		config: &StoplightConfig{Defaults: map[string]map[string]interface{}{
+			OpportunitiesCollection: {"status": "open", "source": "unknown", "monetaryValue": 0.0},
+		}},
+		collection: &base.Collection{SourceID: "source", Type: OpportunitiesCollection},
+	}
+	object := map[string]interface{}{"id": "o1", "status": "won", "source": "  "}
+
+	emptyStringsToNull(object)
+	s.applyDefaults(object)
+	expected := map[string]interface{}{"id": "o1", "status": "won", "source": "unknown", "monetaryValue": 0.0}
+	if !reflect.DeepEqual(object, expected) {
+		t.Errorf("object %v, expected %v", object, expected)
+	}
+}
@@ -0,0 +1,117 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const defaultDeletionDetectionInterval = 24 * time.Hour
+
+// seenIds is the state of the deletion detection of a collection: ids seen during the last full scan
+type seenIds struct {
+	ScannedAt time.Time `json:"scanned_at"`
+	Ids       []string  `json:"ids"`
+}
+
+// pendingSeenIds are the seen ids of a diffed scan, persisted once its objects and tombstones are loaded
+type pendingSeenIds struct {
+	store CursorStore
+	key   string
+	ids   *seenIds
+}
+
+// objectIds returns the ids of the objects
+func objectIds(objects []map[string]interface{}) []string {
+	ids := make([]string, 0, len(objects))
+	for _, object := range objects {
+		ids = append(ids, fmt.Sprint(object["id"]))
+	}
+	return ids
+}
+
+// detectDeletions diffs the ids of a full scan, taken before the objects are validated so quarantined objects
+// aren't deleted, against the ids seen during the previous scan and returns tombstones for the missing ones.
+// Scans are diffed at most once per deletion_detection_interval. The seen ids are pending until commitSeenIds
+// is called after the objects are loaded, so the tombstones of a failed load are detected again
+func (s *Stoplight) detectDeletions(objects []map[string]interface{}, scannedIds []string) ([]map[string]interface{}, error) {
+	s.pendingSeenIds = nil
+
+	interval, err := s.config.deletionDetectionInterval()
+	if err != nil {
+		return nil, err
+	}
+
+	cursors, err := s.cursors()
+	if err != nil {
+		return nil, err
+	}
+	key := s.GetCollectionMetaKey() + "_" + s.config.LocationId + "_ids"
+	previous := &seenIds{}
+	b, err := cursors.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	if b != nil {
+		err = json.Unmarshal(b, previous)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the previous scan tells apart inserted and updated objects, even when it isn't diffed yet
+	if !previous.ScannedAt.IsZero() {
+		previousIds := make(map[string]bool, len(previous.Ids))
+		for _, id := range previous.Ids {
+			previousIds[id] = true
+		}
+		for _, object := range objects {
+			if previousIds[fmt.Sprint(object["id"])] {
+				object[changeTypeField] = ChangeUpdate
+			} else {
+				object[changeTypeField] = ChangeInsert
+			}
+		}
+	}
+
+	now := time.Now()
+	if !previous.ScannedAt.IsZero() && now.Sub(previous.ScannedAt) < interval {
+		return nil, nil
+	}
+
+	current := &seenIds{ScannedAt: now, Ids: scannedIds}
+	currentIds := make(map[string]bool, len(scannedIds))
+	for _, id := range scannedIds {
+		currentIds[id] = true
+	}
+
+	var tombstones []map[string]interface{}
+	for _, id := range previous.Ids {
+		if !currentIds[id] {
+			tombstones = append(tombstones, tombstone(id, now))
+		}
+	}
+
+	s.pendingSeenIds = &pendingSeenIds{store: cursors, key: key, ids: current}
+	return tombstones, nil
+}
+
+// commitSeenIds persists the seen ids of the loaded scan in the cursor store
+func (s *Stoplight) commitSeenIds() error {
+	if s.pendingSeenIds == nil {
+		return nil
+	}
+
+	b, err := json.Marshal(s.pendingSeenIds.ids)
+	if err != nil {
+		return err
+	}
+	err = s.pendingSeenIds.store.Set(s.pendingSeenIds.key, b)
+	if err != nil {
+		return err
+	}
+	s.pendingSeenIds = nil
+	return nil
+}
@@ -0,0 +1,86 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+func scan(ids ...string) []map[string]interface{} {
+	objects := make([]map[string]interface{}, 0, len(ids))
+	for _, id := range ids {
+		objects = append(objects, map[string]interface{}{"id": id})
+	}
+	return objects
+}
+
+func tombstoneIds(tombstones []map[string]interface{}) []string {
+	var ids []string
+	for _, object := range tombstones {
+		if object[deletedField] != true || object[changeTypeField] != ChangeDelete {
+			continue
+		}
+		ids = append(ids, object["id"].(string))
+	}
+	return ids
+}
+
+func TestDetectDeletions(t *testing.T) {
+	config := &StoplightConfig{StateDir: t.TempDir(), DeletionDetection: true, DeletionDetectionInterval: "1ns"}
+	s := &Stoplight{config: config, collection: &base.Collection{SourceID: "source", Name: "contacts", Type: ContactsCollection}}
+
+	tombstones, err := s.detectDeletions(scan("a", "b", "c"), []string{"a", "b", "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tombstones) != 0 {
+		t.Fatalf("first scan deleted %v", tombstoneIds(tombstones))
+	}
+	if err := s.commitSeenIds(); err != nil {
+		t.Fatal(err)
+	}
+
+	objects := scan("a", "d")
+	tombstones, err = s.detectDeletions(objects, []string{"a", "d"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := tombstoneIds(tombstones); len(ids) != 2 || ids[0] != "b" || ids[1] != "c" {
+		t.Errorf("deleted %v, expected [b c]", ids)
+	}
+	if objects[0][changeTypeField] != ChangeUpdate || objects[1][changeTypeField] != ChangeInsert {
+		t.Errorf("change types %v %v, expected update and insert", objects[0][changeTypeField], objects[1][changeTypeField])
+	}
+
+	// the load failed: the seen ids aren't committed and the deletions are detected again
+	tombstones, err = s.detectDeletions(scan("a", "d"), []string{"a", "d"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := tombstoneIds(tombstones); len(ids) != 2 {
+		t.Errorf("deleted %v after a failed load, expected [b c]", ids)
+	}
+}
+
+func TestDetectDeletionsKeepsQuarantined(t *testing.T) {
+	config := &StoplightConfig{StateDir: t.TempDir(), DeletionDetection: true, DeletionDetectionInterval: "1ns"}
+	s := &Stoplight{config: config, collection: &base.Collection{SourceID: "source", Name: "contacts", Type: ContactsCollection}}
+
+	if _, err := s.detectDeletions(scan("a", "b"), []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.commitSeenIds(); err != nil {
+		t.Fatal(err)
+	}
+
+	// b is quarantined by the validation: it is scanned but not loaded
+	tombstones, err := s.detectDeletions(scan("a"), []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tombstones) != 0 {
+		t.Errorf("deleted quarantined %v", tombstoneIds(tombstones))
+	}
+}
@@ -0,0 +1,79 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"errors"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+const (
+	defaultExplosionParentKey  = "id"
+	defaultExplosionForeignKey = "parent_id"
+)
+
+// ArrayExplosion configures a child collection made of the elements of an array field of a parent collection
+// (e.g. opportunities followers). Each element is emitted with a foreign key to its parent and its index in the array.
+// Object elements keep their fields, scalar elements are emitted in a value column
+type ArrayExplosion struct {
+	Collection string `mapstructure:"collection" json:"collection,omitempty" yaml:"collection,omitempty"`
+	Parent     string `mapstructure:"parent" json:"parent,omitempty" yaml:"parent,omitempty"`
+	Field      string `mapstructure:"field" json:"field,omitempty" yaml:"field,omitempty"`
+	ParentKey  string `mapstructure:"parent_key" json:"parent_key,omitempty" yaml:"parent_key,omitempty"`
+	ForeignKey string `mapstructure:"foreign_key" json:"foreign_key,omitempty" yaml:"foreign_key,omitempty"`
+}
+
+// Validate returns an error if the array explosion isn't fully configured
+func (ae *ArrayExplosion) Validate() error {
+	if ae.Collection == "" {
+		return errors.New("Stoplight array explosion collection is required")
+	}
+
+	if ae.Parent == "" {
+		return errors.New("Stoplight array explosion parent is required")
+	}
+
+	if ae.Field == "" {
+		return errors.New("Stoplight array explosion field is required")
+	}
+
+	return nil
+}
+
+// explodeArray returns the elements of the array field of every object of the parent collection in the interval
+func (s *Stoplight) explodeArray(explosion *ArrayExplosion, interval *base.TimeInterval) ([]map[string]interface{}, error) {
+	parents, err := s.getCollectionObjects(explosion.Parent, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	parentKey := explosion.ParentKey
+	if parentKey == "" {
+		parentKey = defaultExplosionParentKey
+	}
+	foreignKey := explosion.ForeignKey
+	if foreignKey == "" {
+		foreignKey = defaultExplosionForeignKey
+	}
+
+	var rows []map[string]interface{}
+	for _, parent := range parents {
+		elements, _ := parent[explosion.Field].([]interface{})
+		for i, element := range elements {
+			row := map[string]interface{}{}
+			if fields, ok := element.(map[string]interface{}); ok {
+				for k, v := range fields {
+					row[k] = v
+				}
+			} else {
+				row["value"] = element
+			}
+			row[foreignKey] = parent[parentKey]
+			row["index"] = i
+			rows = append(rows, row)
+		}
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,50 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+func TestExplodeArray(t *testing.T) {
+	s := &Stoplight{
+		client: fakeApi(map[string]string{
+			"/calendars/": `{"calendars":[{"id":"cal1","teamMembers":[{"userId":"u1"},{"userId":"u2"}],"slots":["9:00"]}]}`,
+		}),
+		ctx:        context.Background(),
+		config:     &StoplightConfig{LocationId: "location1"},
+		collection: &base.Collection{SourceID: "source", Type: "calendar_members"},
+	}
+
+	rows, err := s.explodeArray(&ArrayExplosion{Collection: "calendar_members", Parent: CalendarsCollection, Field: "teamMembers", ForeignKey: "calendar_id"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[1]["userId"] != "u2" || rows[1]["calendar_id"] != "cal1" || rows[1]["index"] != 1 {
+		t.Errorf("exploded %v, expected the 2 team members with calendar_id and index", rows)
+	}
+
+	rows, err = s.explodeArray(&ArrayExplosion{Collection: "calendar_slots", Parent: CalendarsCollection, Field: "slots"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0]["value"] != "9:00" || rows[0]["parent_id"] != "cal1" {
+		t.Errorf("exploded %v, expected the slot in value with parent_id", rows)
+	}
+}
+
+func TestValidateArrayExplosion(t *testing.T) {
+	invalid := []*ArrayExplosion{
+		{Parent: OpportunitiesCollection, Field: "followers"},
+		{Collection: "opportunity_followers", Field: "followers"},
+		{Collection: "opportunity_followers", Parent: OpportunitiesCollection},
+	}
+	for _, explosion := range invalid {
+		if explosion.Validate() == nil {
+			t.Errorf("array explosion %+v is valid", explosion)
+		}
+	}
+}
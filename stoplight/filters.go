@@ -0,0 +1,61 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"fmt"
+
+	"github.com/antonmedv/expr"
+	"github.com/antonmedv/expr/vm"
+	"github.com/jitsucom/jitsu/server/logging"
+)
+
+const (
+	FilterErrorSkip = "skip"
+	FilterErrorFail = "fail"
+)
+
+// compileFilter compiles a filter expression evaluated against each record of a collection,
+// e.g. record.status == "open" && record.monetaryValue > 0
+func compileFilter(expression string) (*vm.Program, error) {
+	return expr.Compile(expression, expr.AsBool())
+}
+
+// filter returns the objects matching the filter expression configured for the collection type in filters.
+// Expressions are evaluated on API field names, before any other transformation. Records the expression can't be
+// evaluated on (e.g. record.monetaryValue > 0 with a null monetaryValue) don't match or fail the sync depending
+// on filter_errors
+func (s *Stoplight) filter(objects []map[string]interface{}) ([]map[string]interface{}, error) {
+	expression, ok := s.config.Filters[s.collection.Type]
+	if !ok {
+		return objects, nil
+	}
+
+	program, err := compileFilter(expression)
+	if err != nil {
+		return nil, fmt.Errorf("Error compiling %s filter: %v", s.collection.Type, err)
+	}
+
+	filtered := objects[:0]
+	for _, object := range objects {
+		// tombstones are always kept so deletions reach the destination
+		if object[deletedField] == true {
+			filtered = append(filtered, object)
+			continue
+		}
+
+		result, err := expr.Run(program, map[string]interface{}{"record": object})
+		if err != nil {
+			if s.config.FilterErrors == FilterErrorFail {
+				return nil, fmt.Errorf("Error evaluating %s filter on object %v: %v", s.collection.Type, object["id"], err)
+			}
+			logging.Warnf("[%s] Skipped %s object %v the filter can't be evaluated on: %v", s.collection.SourceID, s.collection.Type, object["id"], err)
+			continue
+		}
+		if matches, _ := result.(bool); matches {
+			filtered = append(filtered, object)
+		}
+	}
+
+	return filtered, nil
+}
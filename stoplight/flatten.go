@@ -0,0 +1,29 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+const defaultFlattenSeparator = "_"
+
+// flattenObject converts nested objects into columns named by joining the keys path with separator
+// (e.g. dndSettings_SMS_status). Objects nested deeper than maxDepth are kept as is, 0 means unlimited depth.
+// Arrays aren't flattened
+func flattenObject(object map[string]interface{}, separator string, maxDepth int) map[string]interface{} {
+	flat := make(map[string]interface{}, len(object))
+	flattenInto(flat, "", object, separator, maxDepth, 1)
+	return flat
+}
+
+func flattenInto(flat map[string]interface{}, prefix string, object map[string]interface{}, separator string, maxDepth int, depth int) {
+	for key, value := range object {
+		if prefix != "" {
+			key = prefix + separator + key
+		}
+
+		nested, ok := value.(map[string]interface{})
+		if !ok || (maxDepth > 0 && depth > maxDepth) {
+			flat[key] = value
+			continue
+		}
+		flattenInto(flat, key, nested, separator, maxDepth, depth+1)
+	}
+}
@@ -0,0 +1,36 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFlattenObject(t *testing.T) {
+	object := map[string]interface{}{
+		"id":   "c1",
+		"tags": []interface{}{map[string]interface{}{"name": "vip"}},
+		"dndSettings": map[string]interface{}{
+			"SMS": map[string]interface{}{"status": "active"},
+		},
+	}
+
+	expected := map[string]interface{}{
+		"id":                       "c1",
+		"tags":                     []interface{}{map[string]interface{}{"name": "vip"}},
+		"dndSettings__SMS__status": "active",
+	}
+	if flat := flattenObject(object, "__", 0); !reflect.DeepEqual(flat, expected) {
+		t.Errorf("flattened %v, expected %v", flat, expected)
+	}
+
+	expected = map[string]interface{}{
+		"id":              "c1",
+		"tags":            []interface{}{map[string]interface{}{"name": "vip"}},
+		"dndSettings_SMS": map[string]interface{}{"status": "active"},
+	}
+	if flat := flattenObject(object, "_", 1); !reflect.DeepEqual(flat, expected) {
+		t.Errorf("flattened with max depth 1 %v, expected %v", flat, expected)
+	}
+}
@@ -0,0 +1,63 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+const dateLayout = "2006-01-02"
+
+// GetFormSubmissions returns the form submissions of the location submitted in the interval.
+// Answers are flattened into answer_<field> columns, answers_raw keeps the original JSON
+func (s *Stoplight) GetFormSubmissions(interval *base.TimeInterval) ([]map[string]interface{}, error) {
+	url := fmt.Sprintf("%s/forms/submissions?locationId=%s&startAt=%s&endAt=%s", apiURL, s.config.LocationId,
+		interval.LowerEndpoint().Format(dateLayout), interval.UpperEndpoint().Format(dateLayout))
+	submissions, err := s.getAllPages(url, "submissions")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, submission := range submissions {
+		err = flattenAnswers(submission, "others")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return submissions, nil
+}
+
+// flattenAnswers replaces the answers object found under key with one answer_<field> column per answer
+// and an answers_raw column with the answers JSON. Nested answers (files, addresses) are kept as JSON strings
+func flattenAnswers(submission map[string]interface{}, key string) error {
+	answers, ok := submission[key].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	raw, err := json.Marshal(answers)
+	if err != nil {
+		return err
+	}
+	submission["answers_raw"] = string(raw)
+
+	for field, answer := range answers {
+		switch answer.(type) {
+		case map[string]interface{}, []interface{}:
+			b, err := json.Marshal(answer)
+			if err != nil {
+				return err
+			}
+			submission["answer_"+field] = string(b)
+		default:
+			submission["answer_"+field] = answer
+		}
+	}
+	delete(submission, key)
+
+	return nil
+}
@@ -0,0 +1,84 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"fmt"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+// GetFunnels returns the funnel definitions (id, name, domain) of the location with their steps_count
+func (s *Stoplight) GetFunnels() ([]map[string]interface{}, error) {
+	funnels, err := s.getAllOffsets(apiURL+"/funnels/funnel/list?locationId="+s.config.LocationId, "funnels")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, funnel := range funnels {
+		steps, _ := funnel["steps"].([]interface{})
+		funnel["steps_count"] = len(steps)
+	}
+
+	return funnels, nil
+}
+
+// GetFunnelPages returns one (funnel_id, step_id, page_id, name, path) row per page of every funnel step
+func (s *Stoplight) GetFunnelPages() ([]map[string]interface{}, error) {
+	objects, err := s.GetFunnels()
+	if err != nil {
+		return nil, err
+	}
+
+	var funnels []Funnel
+	err = s.decode(objects, &funnels)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []map[string]interface{}
+	for _, funnel := range funnels {
+		for _, step := range funnel.Steps {
+			for _, page := range step.Pages {
+				rows = append(rows, map[string]interface{}{
+					"funnel_id": funnel.Id,
+					"step_id":   step.Id,
+					"page_id":   page,
+					"name":      step.Name,
+					"path":      step.Url,
+				})
+			}
+		}
+	}
+
+	return rows, nil
+}
+
+// GetFunnelStats returns the daily page views and opt-ins (date, funnel_id, page_id) of every funnel in the interval.
+// Statistics aren't exposed for every account: funnels without a stats endpoint are skipped
+func (s *Stoplight) GetFunnelStats(interval *base.TimeInterval) ([]map[string]interface{}, error) {
+	funnels, err := s.GetFunnels()
+	if err != nil {
+		return nil, err
+	}
+
+	var stats []map[string]interface{}
+	for _, funnel := range funnels {
+		url := fmt.Sprintf("%s/funnels/%v/stats?locationId=%s&startAt=%s&endAt=%s", apiURL, funnel["_id"], s.config.LocationId,
+			interval.LowerEndpoint().Format(dateLayout), interval.UpperEndpoint().Format(dateLayout))
+		funnelStats, err := s.getObjects(url, "stats")
+		if err != nil {
+			if isNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		for _, stat := range funnelStats {
+			stat["funnel_id"] = funnel["_id"]
+		}
+		stats = append(stats, funnelStats...)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,99 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/jitsucom/jitsu/server/drivers/base"
+	"google.golang.org/api/option"
+)
+
+const (
+	GCSAuthServiceAccount   = "service_account"
+	GCSAuthWorkloadIdentity = "workload_identity"
+)
+
+// GCSLoaderConfig is the configuration of the Google Cloud Storage loader. With service_account auth (default),
+// the service account key is read from credentials_file or credentials_json. With workload_identity auth, the
+// application default credentials are used: the Kubernetes service account bound to a Google service account
+// on GKE, the instance service account on Compute Engine. Format and compression are the S3 loader ones
+type GCSLoaderConfig struct {
+	Bucket          string `mapstructure:"bucket" json:"bucket,omitempty" yaml:"bucket,omitempty"`
+	Prefix          string `mapstructure:"prefix" json:"prefix,omitempty" yaml:"prefix,omitempty"`
+	Auth            string `mapstructure:"auth" json:"auth,omitempty" yaml:"auth,omitempty"`
+	CredentialsFile string `mapstructure:"credentials_file" json:"credentials_file,omitempty" yaml:"credentials_file,omitempty"`
+	CredentialsJson string `mapstructure:"credentials_json" json:"credentials_json,omitempty" yaml:"credentials_json,omitempty"`
+	Format          string `mapstructure:"format" json:"format,omitempty" yaml:"format,omitempty"`
+	Compression     string `mapstructure:"compression" json:"compression,omitempty" yaml:"compression,omitempty"`
+}
+
+// Validate returns an error if the GCS loader configuration is invalid
+func (gc *GCSLoaderConfig) Validate() error {
+	if gc.Bucket == "" {
+		return errors.New("Stoplight gcs bucket is required")
+	}
+	switch gc.Auth {
+	case "", GCSAuthServiceAccount:
+		if (gc.CredentialsFile == "") == (gc.CredentialsJson == "") {
+			return errors.New("Stoplight gcs credentials_file or credentials_json is required for service_account auth")
+		}
+	case GCSAuthWorkloadIdentity:
+		if gc.CredentialsFile != "" || gc.CredentialsJson != "" {
+			return errors.New("Stoplight gcs credentials aren't used by workload_identity auth")
+		}
+	default:
+		return fmt.Errorf("Stoplight gcs auth %q is not supported: use service_account or workload_identity", gc.Auth)
+	}
+	return validateObjectFormat("gcs", gc.Format, gc.Compression)
+}
+
+// NewGCSLoader returns the ObjectsLoader writing the loaded objects of the collection to Google Cloud Storage,
+// partitioned by day under <prefix>/<collection>/date=YYYY-MM-DD/
+func NewGCSLoader(ctx context.Context, config *GCSLoaderConfig, collection string) (base.ObjectsLoader, error) {
+	err := config.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	var options []option.ClientOption
+	switch {
+	case config.CredentialsFile != "":
+		options = append(options, option.WithCredentialsFile(config.CredentialsFile))
+	case config.CredentialsJson != "":
+		options = append(options, option.WithCredentialsJSON([]byte(config.CredentialsJson)))
+	}
+	client, err := storage.NewClient(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating GCS client: %v", err)
+	}
+	bucket := client.Bucket(config.Bucket)
+
+	format := config.Format
+	if format == "" {
+		format = FormatNdjson
+	}
+	return &partitionedLoader{
+		collection:  collection,
+		prefix:      strings.Trim(config.Prefix, "/"),
+		format:      format,
+		compression: config.Compression,
+		upload: func(key string, body []byte, contentType string) error {
+			// cancelling the context aborts the upload of a failed write
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+
+			writer := bucket.Object(key).NewWriter(ctx)
+			writer.ContentType = contentType
+			if _, err := writer.Write(body); err != nil {
+				return err
+			}
+			// the object is only created once the writer is closed
+			return writer.Close()
+		},
+	}, nil
+}
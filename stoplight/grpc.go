@@ -0,0 +1,110 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"encoding/json"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// recordStreamServiceDesc is the stoplight.RecordStream gRPC service of stoplight.proto. It only uses protobuf
+// well-known types so any gRPC client can consume it with the proto file
+var recordStreamServiceDesc = grpc.ServiceDesc{
+	ServiceName: "stoplight.RecordStream",
+	HandlerType: (*recordStreamService)(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Subscribe",
+			Handler:       subscribeHandler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "stoplight.proto",
+}
+
+// recordStreamService is the implementation of the stoplight.RecordStream gRPC service
+type recordStreamService interface {
+	Subscribe(collections *structpb.ListValue, stream grpc.ServerStream) error
+}
+
+// recordStreamServer serves the records of a RecordStream
+type recordStreamServer struct {
+	stream *RecordStream
+}
+
+// RegisterRecordStreamServer registers the stoplight.RecordStream gRPC service streaming the records
+// of the record stream on the server
+func RegisterRecordStreamServer(server grpc.ServiceRegistrar, stream *RecordStream) {
+	server.RegisterService(&recordStreamServiceDesc, &recordStreamServer{stream: stream})
+}
+
+func subscribeHandler(srv interface{}, stream grpc.ServerStream) error {
+	collections := &structpb.ListValue{}
+	if err := stream.RecvMsg(collections); err != nil {
+		return err
+	}
+	return srv.(recordStreamService).Subscribe(collections, stream)
+}
+
+// Subscribe streams the records of the requested collections (every collection if the list is empty) as
+// {"source_id", "collection", "record"} structs until the client cancels
+func (rss *recordStreamServer) Subscribe(collections *structpb.ListValue, stream grpc.ServerStream) error {
+	var names []string
+	for _, value := range collections.GetValues() {
+		name := value.GetStringValue()
+		if name == "" {
+			return status.Error(codes.InvalidArgument, "collections must be strings")
+		}
+		names = append(names, name)
+	}
+
+	subscription := rss.stream.Subscribe(names...)
+	defer subscription.Close()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case record, ok := <-subscription.Records():
+			if !ok {
+				if err := subscription.Err(); err != nil {
+					return status.Error(codes.ResourceExhausted, err.Error())
+				}
+				return nil
+			}
+
+			message, err := recordMessage(record)
+			if err != nil {
+				return status.Error(codes.Internal, err.Error())
+			}
+			err = stream.SendMsg(message)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// recordMessage converts the record into a protobuf struct. The record goes through JSON first
+// so every value is a JSON one
+func recordMessage(record *StreamRecord) (*structpb.Struct, error) {
+	b, err := json.Marshal(record.Record)
+	if err != nil {
+		return nil, err
+	}
+	var object map[string]interface{}
+	err = json.Unmarshal(b, &object)
+	if err != nil {
+		return nil, err
+	}
+
+	return structpb.NewStruct(map[string]interface{}{
+		"source_id":  record.SourceId,
+		"collection": record.Collection,
+		"record":     object,
+	})
+}
@@ -0,0 +1,85 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+// personCollections are the collections where a name field is the name of a person
+var personCollections = []string{ContactsCollection, FormSubmissionsCollection, SurveySubmissionsCollection, DuplicateContactsCollection}
+
+// hipaaRules are the masking rules of hipaa_mode: emails and phones are hashed so records stay joinable,
+// names, addresses, birth dates, message or note bodies and unpivoted custom field values are dropped.
+// The fields are masked in the nested objects too (the contact of opportunities...)
+var hipaaRules = []*MaskingRule{
+	{Field: "email", Policy: MaskHash},
+	{Field: "additionalEmails", Policy: MaskHash},
+	{Field: "phone", Policy: MaskHash},
+	{Field: "phoneNo", Policy: MaskHash},
+	{Field: "additionalPhones", Policy: MaskHash},
+	{Field: "firstName", Policy: MaskDrop},
+	{Field: "lastName", Policy: MaskDrop},
+	{Field: "firstNameLowerCase", Policy: MaskDrop},
+	{Field: "lastNameLowerCase", Policy: MaskDrop},
+	{Field: "fullNameLowerCase", Policy: MaskDrop},
+	{Field: "contactName", Policy: MaskDrop},
+	{Field: "name", Policy: MaskDrop, Collections: personCollections},
+	{Field: "contact.name", Policy: MaskDrop},
+	{Field: "contactDetails.name", Policy: MaskDrop},
+	{Field: "address1", Policy: MaskDrop},
+	{Field: "address", Policy: MaskDrop},
+	{Field: "city", Policy: MaskDrop},
+	{Field: "postalCode", Policy: MaskDrop},
+	{Field: "dateOfBirth", Policy: MaskDrop},
+	{Field: "body", Policy: MaskDrop},
+	{Field: "message", Policy: MaskDrop},
+	{Field: "messageBody", Policy: MaskDrop},
+	{Field: "notes", Policy: MaskDrop},
+	{Field: "customFields", Policy: MaskDrop},
+	{Field: "value", Policy: MaskDrop, Collections: []string{ContactCustomFieldsCollection}},
+}
+
+// hipaaCustomFieldPolicy returns the hipaa_mode masking policy of the pivoted custom fields of the data type:
+// emails and phones are hashed, numbers, amounts and options are kept and free text, dates (birth dates...)
+// and files are dropped. It returns an empty policy for kept fields
+func hipaaCustomFieldPolicy(dataType string) string {
+	switch dataType {
+	case "EMAIL", "PHONE":
+		return MaskHash
+	case "NUMERICAL", "MONETORY", "CHECKBOX", "SINGLE_OPTIONS", "MULTIPLE_OPTIONS", "RADIO":
+		return ""
+	default:
+		return MaskDrop
+	}
+}
+
+// maskingRules returns the masking rules to apply to the collection type: the configured ones and, in hipaa_mode,
+// the HIPAA preset rules of the fields without a configured rule for the collection
+func (stc *StoplightConfig) maskingRules(collectionType string) []*MaskingRule {
+	var rules []*MaskingRule
+	configured := make(map[string]bool, len(stc.MaskingRules))
+	for _, rule := range stc.MaskingRules {
+		if rule.appliesTo(collectionType) {
+			rules = append(rules, rule)
+			configured[rule.Field] = true
+		}
+	}
+	if !stc.HipaaMode {
+		return rules
+	}
+
+	for _, rule := range hipaaRules {
+		if rule.appliesTo(collectionType) && !configured[rule.Field] {
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules
+}
+
+// maskingConfigured returns true if a masking rule is configured for the field of the collection type
+func (stc *StoplightConfig) maskingConfigured(collectionType string, field string) bool {
+	for _, rule := range stc.MaskingRules {
+		if rule.Field == field && rule.appliesTo(collectionType) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// recordVersions is the cursor state shared by the webhooks and the reconciliation polls in hybrid mode:
+// the update time of the last loaded version of the records by id
+type recordVersions struct {
+	mutex    sync.Mutex
+	store    CursorStore
+	key      string
+	Versions map[string]time.Time `json:"versions"`
+}
+
+// recordVersionsKey identifies the record versions of a collection and location in a cursor store
+type recordVersionsKey struct {
+	store CursorStore
+	key   string
+}
+
+var (
+	recordVersionsMutex sync.Mutex
+	recordVersionsByKey = map[recordVersionsKey]*recordVersions{}
+)
+
+// hybrid returns true if webhooks and polls share their cursor state
+func (s *Stoplight) hybrid() bool {
+	return s.config.Webhooks != nil && s.config.Webhooks.Hybrid
+}
+
+// pendingVersions are the versions of the objects kept from a batch (a poll or webhook events), recorded
+// once they are loaded. Batches routed concurrently have their own pending versions
+type pendingVersions struct {
+	versions *recordVersions
+	ids      map[string]time.Time
+}
+
+// loadedVersions returns the record versions of the driver collection type in the location, shared by every
+// driver instance of the source: the polling driver of the collection and the drivers routing the webhook events
+// to it. They are read from the cursor store once
+func (s *Stoplight) loadedVersions(locationId string) (*recordVersions, error) {
+	cursors, err := s.cursors()
+	if err != nil {
+		return nil, err
+	}
+	key := recordVersionsKey{store: cursors, key: s.collection.SourceID + "_" + s.collection.Type + "_" + locationId + "_versions"}
+
+	recordVersionsMutex.Lock()
+	defer recordVersionsMutex.Unlock()
+
+	if versions, ok := recordVersionsByKey[key]; ok {
+		return versions, nil
+	}
+
+	versions := &recordVersions{store: cursors, key: key.key, Versions: map[string]time.Time{}}
+	b, err := cursors.Get(key.key)
+	if err != nil {
+		return nil, err
+	}
+	if b != nil {
+		err = json.Unmarshal(b, versions)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	recordVersionsByKey[key] = versions
+	return versions, nil
+}
+
+// skipLoadedVersions drops the objects of the location whose version (update time) was already loaded by the
+// webhooks or a previous poll. The versions of the kept objects are returned pending: commit them after the objects
+// are loaded. Objects without update time and tombstones are always kept
+func (s *Stoplight) skipLoadedVersions(objects []map[string]interface{}, locationId string) ([]map[string]interface{}, *pendingVersions, error) {
+	versions, err := s.loadedVersions(locationId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	versions.mutex.Lock()
+	defer versions.mutex.Unlock()
+
+	pending := &pendingVersions{versions: versions, ids: map[string]time.Time{}}
+	kept := objects[:0]
+	for _, object := range objects {
+		id := fmt.Sprint(object["id"])
+		if object[deletedField] == true {
+			// zero version: the record is forgotten once the tombstone is loaded
+			pending.ids[id] = time.Time{}
+			kept = append(kept, object)
+			continue
+		}
+
+		version := updatedAt(object)
+		if version.IsZero() {
+			kept = append(kept, object)
+			continue
+		}
+		if loaded, ok := versions.Versions[id]; ok && !version.After(loaded) {
+			continue
+		}
+
+		pending.ids[id] = version
+		kept = append(kept, object)
+	}
+
+	return kept, pending, nil
+}
+
+// commitLoadedVersions records the pending versions of the objects of the last poll once they are loaded
+func (s *Stoplight) commitLoadedVersions() error {
+	err := s.pendingVersions.commit()
+	if err != nil {
+		return err
+	}
+	s.pendingVersions = nil
+	return nil
+}
+
+// commit records the pending versions of the loaded objects and persists them in the cursor store
+func (pv *pendingVersions) commit() error {
+	if pv == nil || len(pv.ids) == 0 {
+		return nil
+	}
+
+	versions := pv.versions
+	versions.mutex.Lock()
+	defer versions.mutex.Unlock()
+
+	for id, version := range pv.ids {
+		if version.IsZero() {
+			delete(versions.Versions, id)
+		} else if version.After(versions.Versions[id]) {
+			versions.Versions[id] = version
+		}
+	}
+
+	b, err := json.Marshal(versions)
+	if err != nil {
+		return err
+	}
+	return versions.store.Set(versions.key, b)
+}
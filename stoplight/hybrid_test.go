@@ -0,0 +1,117 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+func TestWebhookVersionSkippedByPoll(t *testing.T) {
+	config := &StoplightConfig{
+		LocationIds: []string{"location1", "location2"},
+		StateDir:    t.TempDir(),
+		Currency:    "USD",
+		Webhooks:    &WebhookConfig{Hybrid: true},
+	}
+	webhooks := &Stoplight{config: config, collection: &base.Collection{SourceID: "source", Name: "webhooks", Type: OpportunitiesCollection}}
+	poll := &Stoplight{config: config, collection: &base.Collection{SourceID: "source", Name: "opportunities", Type: OpportunitiesCollection}}
+
+	event := &WebhookEvent{Type: "OpportunityUpdate", LocationId: "location2", Payload: map[string]interface{}{
+		"id":        "o1",
+		"updatedAt": "2023-03-01T10:00:00Z",
+	}}
+	_, objects, err := webhooks.RouteWebhookEvent(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 1 {
+		t.Fatalf("routed %d objects, expected 1", len(objects))
+	}
+
+	polled := func() []map[string]interface{} {
+		return []map[string]interface{}{
+			{"id": "o1", "updatedAt": "2023-03-01T10:00:00Z"},
+			{"id": "o2", "updatedAt": "2023-03-01T10:00:00Z"},
+		}
+	}
+	kept, _, err := poll.skipLoadedVersions(polled(), "location2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kept) != 1 || kept[0]["id"] != "o2" {
+		t.Errorf("poll of the webhook location kept %v, expected o2 only", kept)
+	}
+
+	kept, _, err = poll.skipLoadedVersions(polled(), "location1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kept) != 2 {
+		t.Errorf("poll of another location kept %v, expected o1 and o2", kept)
+	}
+}
+
+func TestNewerVersionNotSkipped(t *testing.T) {
+	config := &StoplightConfig{StateDir: t.TempDir(), Webhooks: &WebhookConfig{Hybrid: true}}
+	s := &Stoplight{config: config, collection: &base.Collection{SourceID: "source", Name: "contacts", Type: ContactsCollection}}
+
+	kept, pending, err := s.skipLoadedVersions([]map[string]interface{}{{"id": "c1", "updatedAt": "2023-03-01T10:00:00Z"}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kept) != 1 {
+		t.Fatalf("kept %d objects, expected 1", len(kept))
+	}
+	if err := pending.commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	kept, _, err = s.skipLoadedVersions([]map[string]interface{}{
+		{"id": "c1", "updatedAt": "2023-03-01T10:00:00Z"},
+		{"id": "c1", "updatedAt": "2023-03-01T11:00:00Z"},
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kept) != 1 || kept[0]["updatedAt"] != "2023-03-01T11:00:00Z" {
+		t.Errorf("kept %v, expected the 11:00 version only", kept)
+	}
+}
+
+func TestConcurrentWebhookVersions(t *testing.T) {
+	config := &StoplightConfig{StateDir: t.TempDir(), Webhooks: &WebhookConfig{Hybrid: true}}
+	s := &Stoplight{config: config, collection: &base.Collection{SourceID: "source", Name: "contacts", Type: ContactsCollection}}
+
+	const events = 50
+	var wg sync.WaitGroup
+	for i := 0; i < events; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			event := &WebhookEvent{Type: "ContactUpdate", Payload: map[string]interface{}{
+				"id":        fmt.Sprintf("c%d", i),
+				"updatedAt": "2023-03-01T10:00:00Z",
+			}}
+			if _, _, err := s.RouteWebhookEvent(event); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	var polled []map[string]interface{}
+	for i := 0; i < events; i++ {
+		polled = append(polled, map[string]interface{}{"id": fmt.Sprintf("c%d", i), "updatedAt": "2023-03-01T10:00:00Z"})
+	}
+	kept, _, err := s.skipLoadedVersions(polled, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kept) != 0 {
+		t.Errorf("%d versions loaded by the webhooks are lost", len(kept))
+	}
+}
@@ -0,0 +1,62 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// idempotencyKeyField is the column of the idempotency key of the records. Records are delivered at least once:
+// a batch may be loaded again when a sync or a webhook replay is retried, so destinations must merge (upsert)
+// the records on this key rather than append them
+const idempotencyKeyField = "_idempotency_key"
+
+// idempotencyKey returns the hex SHA-256 of the collection, the record surrogate key (or id) and its version:
+// the update time, the deletion for tombstones or the record content when the update time isn't known
+func idempotencyKey(collection string, object map[string]interface{}) string {
+	id := recordKey(object)
+
+	var version string
+	switch {
+	case object[deletedField] == true:
+		version = "deleted"
+	case id != nil && !updatedAt(object).IsZero():
+		version = updatedAt(object).UTC().Format(time.RFC3339Nano)
+	default:
+		// maps are encoded with sorted keys: the same content always has the same version. The driver metadata
+		// (sync time, sync run id...) changes on every sync and isn't part of the content
+		content := make(map[string]interface{}, len(object))
+		for k, v := range object {
+			if !metadataFields[k] {
+				content[k] = v
+			}
+		}
+		b, _ := json.Marshal(content)
+		sum := sha256.Sum256(b)
+		version = hex.EncodeToString(sum[:])
+	}
+
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x1f%v\x1f%s", collection, id, version)))
+	return hex.EncodeToString(sum[:])
+}
+
+// addIdempotencyKeys sets the idempotency key of the objects and drops the objects whose key is already
+// in the batch, so a batch never loads the same record version twice
+func (s *Stoplight) addIdempotencyKeys(objects []map[string]interface{}) []map[string]interface{} {
+	seen := make(map[string]bool, len(objects))
+	kept := objects[:0]
+	for _, object := range objects {
+		key := idempotencyKey(s.collection.Type, object)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		object[idempotencyKeyField] = key
+		kept = append(kept, object)
+	}
+	return kept
+}
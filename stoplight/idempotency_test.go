@@ -0,0 +1,36 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+func TestIdempotencyKeyIgnoresMetadata(t *testing.T) {
+	first := map[string]interface{}{"_id": "p1", "name": "Widget", syncedAtField: "2023-03-01T10:00:00Z", syncRunIdField: "run1"}
+	second := map[string]interface{}{"_id": "p1", "name": "Widget", syncedAtField: "2023-03-02T10:00:00Z", syncRunIdField: "run2"}
+	changed := map[string]interface{}{"_id": "p1", "name": "Gadget", syncedAtField: "2023-03-02T10:00:00Z", syncRunIdField: "run2"}
+
+	if idempotencyKey(ProductsCollection, first) != idempotencyKey(ProductsCollection, second) {
+		t.Error("the same record synced twice has different idempotency keys")
+	}
+	if idempotencyKey(ProductsCollection, first) == idempotencyKey(ProductsCollection, changed) {
+		t.Error("changed records have the same idempotency key")
+	}
+}
+
+func TestIdempotencyKeyOfChildRows(t *testing.T) {
+	s := &Stoplight{config: &StoplightConfig{}, collection: &base.Collection{SourceID: "source", Type: CourseOffersCollection}}
+	objects := []map[string]interface{}{
+		{"id": "offer1", "product_id": "p1", "updatedAt": "2023-03-01T10:00:00Z"},
+		{"id": "offer1", "product_id": "p2", "updatedAt": "2023-03-01T10:00:00Z"},
+	}
+
+	s.addSurrogateKeys(objects)
+	objects = s.addIdempotencyKeys(objects)
+	if len(objects) != 2 {
+		t.Errorf("kept %v, expected a row per product", objects)
+	}
+}
@@ -0,0 +1,61 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// isIdField returns true for id fields: id, _id and fields ending with _id or Id (contact_id, contactId)
+func isIdField(field string) bool {
+	return field == "id" || strings.HasSuffix(field, "_id") || strings.HasSuffix(field, "Id")
+}
+
+// objectId returns the id of the object: id or, for the endpoints keyed by MongoDB ids (products, invoices,
+// funnels...), _id. It returns nil for objects without id
+func objectId(object map[string]interface{}) interface{} {
+	if id, ok := object["id"]; ok && id != nil {
+		return id
+	}
+	return object["_id"]
+}
+
+// recordKey returns the key of the record in its collection: the surrogate key (_key) of the child collections,
+// whose rows may share the id of their parent (e.g. a course offer per product), or the object id
+func recordKey(object map[string]interface{}) interface{} {
+	if key, ok := object[surrogateKeyField]; ok && key != nil {
+		return key
+	}
+	return objectId(object)
+}
+
+// stringifyIds converts the non-null id fields of the object and of its nested objects to strings
+func stringifyIds(object map[string]interface{}) {
+	for field, value := range object {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			stringifyIds(v)
+		case []interface{}:
+			for _, element := range v {
+				if nested, ok := element.(map[string]interface{}); ok {
+					stringifyIds(nested)
+				}
+			}
+		case nil, string:
+		default:
+			if isIdField(field) {
+				object[field] = idString(v)
+			}
+		}
+	}
+}
+
+// idString formats an id value as a string, numbers are formatted without exponent or decimals
+func idString(value interface{}) string {
+	if f, ok := value.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
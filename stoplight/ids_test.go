@@ -0,0 +1,32 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStringifyIds(t *testing.T) {
+	object := map[string]interface{}{
+		"id":        1234567890123.0,
+		"contactId": 42.0,
+		"count":     3.0,
+		"user_id":   nil,
+		"contact":   map[string]interface{}{"id": 7.0},
+		"items":     []interface{}{map[string]interface{}{"product_id": 12.0}},
+	}
+
+	stringifyIds(object)
+	expected := map[string]interface{}{
+		"id":        "1234567890123",
+		"contactId": "42",
+		"count":     3.0,
+		"user_id":   nil,
+		"contact":   map[string]interface{}{"id": "7"},
+		"items":     []interface{}{map[string]interface{}{"product_id": "12"}},
+	}
+	if !reflect.DeepEqual(object, expected) {
+		t.Errorf("stringified %v, expected %v", object, expected)
+	}
+}
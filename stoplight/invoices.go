@@ -0,0 +1,63 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"fmt"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+// GetInvoices returns the invoices (number, contact, status, amounts, dates) of the location issued in the interval
+func (s *Stoplight) GetInvoices(interval *base.TimeInterval) ([]map[string]interface{}, error) {
+	url := fmt.Sprintf("%s/invoices/?altType=location&altId=%s&startAt=%s&endAt=%s", apiURL, s.config.LocationId,
+		interval.LowerEndpoint().Format(dateLayout), interval.UpperEndpoint().Format(dateLayout))
+	return s.getAllOffsets(url, "invoices")
+}
+
+// GetInvoiceItems returns the line items of the invoices issued in the interval with their invoice_id
+func (s *Stoplight) GetInvoiceItems(interval *base.TimeInterval) ([]map[string]interface{}, error) {
+	invoices, err := s.GetInvoices(interval)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []map[string]interface{}
+	for _, invoice := range invoices {
+		items, ok := invoice["invoiceItems"].([]interface{})
+		if !ok {
+			continue
+		}
+		for _, i := range items {
+			item, ok := i.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			item["invoice_id"] = invoice["_id"]
+			rows = append(rows, item)
+		}
+	}
+
+	return rows, nil
+}
+
+// GetInvoiceTemplates returns the invoice templates of the location
+func (s *Stoplight) GetInvoiceTemplates() ([]map[string]interface{}, error) {
+	return s.getAllOffsets(apiURL+"/invoices/template?altType=location&altId="+s.config.LocationId, "data")
+}
+
+// GetEstimates returns the estimates of the location issued in the interval. Estimates aren't available
+// for every account: an empty list is returned when the endpoint doesn't exist
+func (s *Stoplight) GetEstimates(interval *base.TimeInterval) ([]map[string]interface{}, error) {
+	url := fmt.Sprintf("%s/invoices/estimate/list?altType=location&altId=%s&startAt=%s&endAt=%s", apiURL, s.config.LocationId,
+		interval.LowerEndpoint().Format(dateLayout), interval.UpperEndpoint().Format(dateLayout))
+	estimates, err := s.getAllOffsets(url, "estimates")
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return estimates, nil
+}
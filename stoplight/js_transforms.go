@@ -0,0 +1,165 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+const (
+	defaultJsTransformTimeout   = 100 * time.Millisecond
+	defaultJsTransformMaxMemory = 64 * 1024 * 1024
+	jsMaxCallStackSize          = 256
+)
+
+// jsTransform is a compiled JavaScript transform of a collection. goja runtimes aren't safe for concurrent use:
+// every execution takes a runtime of the pool, so the collection drivers and the webhook partitions sharing the
+// transformer run the transform concurrently in their own runtime
+type jsTransform struct {
+	program   *goja.Program
+	timeout   time.Duration
+	maxMemory uint64
+
+	mutex    sync.Mutex
+	runtimes []*jsRuntime
+}
+
+// jsRuntime is a goja runtime of the pool with the transform function
+type jsRuntime struct {
+	runtime  *goja.Runtime
+	function goja.Callable
+}
+
+// newJsTransformer returns a Transformer executing the JavaScript snippets configured per collection in js_transforms.
+// A snippet is the body of a function receiving the record and returning the transformed record or null to drop it,
+// e.g. "record.full_name = record.firstName + ' ' + record.lastName; return record". goja can't measure the memory
+// of a runtime: the memory of an execution is bounded by its input and output records, which can't exceed maxMemory
+// bytes once JSON encoded, its timeout after which the runtime is interrupted and the limited call stack size
+func newJsTransformer(snippets map[string]string, timeout time.Duration, maxMemory int) (Transformer, error) {
+	if timeout <= 0 {
+		timeout = defaultJsTransformTimeout
+	}
+	if maxMemory <= 0 {
+		maxMemory = defaultJsTransformMaxMemory
+	}
+
+	transforms := make(map[string]*jsTransform, len(snippets))
+	for collection, snippet := range snippets {
+		program, err := goja.Compile(collection, "(function(record) {\n"+snippet+"\n})", false)
+		if err != nil {
+			return nil, fmt.Errorf("Error compiling %s js_transform: %v", collection, err)
+		}
+		transform := &jsTransform{program: program, timeout: timeout, maxMemory: uint64(maxMemory)}
+
+		// the first runtime checks the snippet is a function body
+		runtime, err := transform.newRuntime()
+		if err != nil {
+			return nil, fmt.Errorf("Stoplight %s js_transform: %v", collection, err)
+		}
+		transform.release(runtime)
+		transforms[collection] = transform
+	}
+
+	return func(collection string, record map[string]interface{}) (map[string]interface{}, error) {
+		transform, ok := transforms[collection]
+		if !ok {
+			return record, nil
+		}
+		return transform.apply(record)
+	}, nil
+}
+
+// newRuntime returns a new runtime executing the transform program
+func (jt *jsTransform) newRuntime() (*jsRuntime, error) {
+	runtime := goja.New()
+	runtime.SetMaxCallStackSize(jsMaxCallStackSize)
+
+	value, err := runtime.RunProgram(jt.program)
+	if err != nil {
+		return nil, err
+	}
+	function, ok := goja.AssertFunction(value)
+	if !ok {
+		return nil, fmt.Errorf("js_transform isn't a function body")
+	}
+
+	return &jsRuntime{runtime: runtime, function: function}, nil
+}
+
+// acquire takes a runtime of the pool or creates one if every runtime is in use
+func (jt *jsTransform) acquire() (*jsRuntime, error) {
+	jt.mutex.Lock()
+	if n := len(jt.runtimes); n > 0 {
+		runtime := jt.runtimes[n-1]
+		jt.runtimes = jt.runtimes[:n-1]
+		jt.mutex.Unlock()
+		return runtime, nil
+	}
+	jt.mutex.Unlock()
+
+	return jt.newRuntime()
+}
+
+// release returns the runtime to the pool
+func (jt *jsTransform) release(runtime *jsRuntime) {
+	jt.mutex.Lock()
+	defer jt.mutex.Unlock()
+
+	jt.runtimes = append(jt.runtimes, runtime)
+}
+
+// apply executes the transform on the record
+func (jt *jsTransform) apply(record map[string]interface{}) (map[string]interface{}, error) {
+	err := jt.checkSize("record", record)
+	if err != nil {
+		return nil, err
+	}
+
+	runtime, err := jt.acquire()
+	if err != nil {
+		return nil, fmt.Errorf("Error creating js_transform runtime: %v", err)
+	}
+
+	timer := time.AfterFunc(jt.timeout, func() {
+		runtime.runtime.Interrupt("js_transform timeout")
+	})
+	result, err := runtime.function(goja.Undefined(), runtime.runtime.ToValue(record))
+	// an interrupted runtime may hold the memory allocated by the snippet: it isn't reused
+	if timer.Stop() {
+		jt.release(runtime)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error executing js_transform: %v", err)
+	}
+
+	if goja.IsNull(result) || goja.IsUndefined(result) {
+		return nil, nil
+	}
+	transformed, ok := result.Export().(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Stoplight js_transform must return an object or null, got %s", result.ExportType())
+	}
+	err = jt.checkSize("transformed record", transformed)
+	if err != nil {
+		return nil, err
+	}
+
+	return transformed, nil
+}
+
+// checkSize returns an error if the JSON encoded record exceeds the memory limit of the transform
+func (jt *jsTransform) checkSize(name string, record map[string]interface{}) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("Error encoding js_transform %s: %v", name, err)
+	}
+	if uint64(len(b)) > jt.maxMemory {
+		return fmt.Errorf("Stoplight js_transform %s %v of %d bytes exceeds js_transform_max_memory", name, objectId(record), len(b))
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJsTransformRecordSizeLimit(t *testing.T) {
+	transform := &jsTransform{maxMemory: 64}
+
+	if err := transform.checkSize("record", map[string]interface{}{"id": "c1"}); err != nil {
+		t.Errorf("small record exceeds the limit: %v", err)
+	}
+
+	_, err := transform.apply(map[string]interface{}{"id": "c1", "notes": strings.Repeat("x", 100)})
+	if err == nil || !strings.Contains(err.Error(), "js_transform_max_memory") {
+		t.Errorf("error %v, expected the record to exceed js_transform_max_memory", err)
+	}
+}
@@ -0,0 +1,169 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	MaskDrop   = "drop"
+	MaskHash   = "hash"
+	MaskRedact = "redact"
+)
+
+// MaskingRule configures the masking policy of a field: drop removes the field, hash replaces the value with its
+// salted SHA-256 hex digest, redact keeps only the last characters (or the first character and the domain of emails).
+// The rule applies to every collection if collections is empty
+type MaskingRule struct {
+	Field       string   `mapstructure:"field" json:"field,omitempty" yaml:"field,omitempty"`
+	Policy      string   `mapstructure:"policy" json:"policy,omitempty" yaml:"policy,omitempty"`
+	Collections []string `mapstructure:"collections" json:"collections,omitempty" yaml:"collections,omitempty"`
+}
+
+// Validate returns an error if the masking rule field or policy is invalid
+func (mr *MaskingRule) Validate() error {
+	if mr.Field == "" {
+		return errors.New("Stoplight masking rule field is required")
+	}
+
+	switch mr.Policy {
+	case MaskDrop, MaskHash, MaskRedact:
+		return nil
+	default:
+		return fmt.Errorf("Stoplight masking rule policy %q of field %s is not supported", mr.Policy, mr.Field)
+	}
+}
+
+// appliesTo returns true if the rule applies to the collection type
+func (mr *MaskingRule) appliesTo(collectionType string) bool {
+	if len(mr.Collections) == 0 {
+		return true
+	}
+	for _, collection := range mr.Collections {
+		if collection == collectionType {
+			return true
+		}
+	}
+	return false
+}
+
+// mask applies the masking rules to the object fields. The field of a rule is either a field name (first_name),
+// masked in the nested objects too, or a dot separated path of nested fields (contact.email) also matching
+// the columns flattened with the separator (contact_email). Only dots separate the keys of a path
+func mask(object map[string]interface{}, rules []*MaskingRule, salt string, separator string) {
+	for _, rule := range rules {
+		if strings.Contains(rule.Field, ".") {
+			maskPath(object, strings.Split(rule.Field, "."), rule, salt, separator)
+			continue
+		}
+		maskField(object, rule, salt)
+	}
+}
+
+// maskField masks the rule field of the objects of the value, at any depth
+func maskField(value interface{}, rule *MaskingRule, salt string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range v {
+			if key == rule.Field {
+				maskKey(v, key, rule.Policy, salt)
+				continue
+			}
+			maskField(nested, rule, salt)
+		}
+	case []interface{}:
+		for _, element := range v {
+			maskField(element, rule, salt)
+		}
+	}
+}
+
+// maskPath masks the field at the path (keys) of the objects of the value. A key of the objects may be
+// several keys of the path flattened with the separator
+func maskPath(value interface{}, path []string, rule *MaskingRule, salt string, separator string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range v {
+			for i := 1; i <= len(path); i++ {
+				if i > 1 && separator == "" {
+					break
+				}
+				if key != strings.Join(path[:i], separator) {
+					continue
+				}
+				if i == len(path) {
+					maskKey(v, key, rule.Policy, salt)
+				} else {
+					maskPath(nested, path[i:], rule, salt, separator)
+				}
+				break
+			}
+		}
+	case []interface{}:
+		for _, element := range v {
+			maskPath(element, path, rule, salt, separator)
+		}
+	}
+}
+
+// maskKey applies the masking policy to the key of the object
+func maskKey(object map[string]interface{}, key string, policy string, salt string) {
+	if policy == MaskDrop {
+		delete(object, key)
+		return
+	}
+	object[key] = maskValue(object[key], policy, salt)
+}
+
+func maskValue(value interface{}, policy string, salt string) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		masked := make([]interface{}, len(v))
+		for i, element := range v {
+			masked[i] = maskValue(element, policy, salt)
+		}
+		return masked
+	case string:
+		if v == "" {
+			return v
+		}
+		if policy == MaskHash {
+			return hashValue(v, salt)
+		}
+		return redact(v)
+	default:
+		if policy == MaskHash {
+			return hashValue(fmt.Sprint(v), salt)
+		}
+		return redact(fmt.Sprint(v))
+	}
+}
+
+// hashValue returns the hex SHA-256 digest of the salted value
+func hashValue(value string, salt string) string {
+	sum := sha256.Sum256([]byte(salt + value))
+	return hex.EncodeToString(sum[:])
+}
+
+// redact replaces the value characters with * keeping the first character and the domain of emails
+// (j***@example.com) and the last 4 characters of other values
+func redact(value string) string {
+	runes := []rune(value)
+	if at := strings.LastIndex(value, "@"); at > 0 {
+		local := []rune(value[:at])
+		return string(local[0]) + strings.Repeat("*", len(local)-1) + value[at:]
+	}
+
+	keep := 4
+	if len(runes) <= keep {
+		return strings.Repeat("*", len(runes))
+	}
+	return strings.Repeat("*", len(runes)-keep) + string(runes[len(runes)-keep:])
+}
@@ -0,0 +1,205 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+func TestMaskPolicies(t *testing.T) {
+	object := map[string]interface{}{
+		"email":     "john@example.com",
+		"phone":     "+15551234567",
+		"firstName": "John",
+		"tags":      []interface{}{"ann@example.com", "bob@example.com"},
+	}
+	rules := []*MaskingRule{
+		{Field: "email", Policy: MaskHash},
+		{Field: "phone", Policy: MaskRedact},
+		{Field: "firstName", Policy: MaskDrop},
+		{Field: "tags", Policy: MaskRedact},
+	}
+	mask(object, rules, "salt", "_")
+
+	if object["email"] != hashValue("john@example.com", "salt") {
+		t.Errorf("email %v isn't hashed", object["email"])
+	}
+	if object["phone"] != "********4567" {
+		t.Errorf("phone %v isn't redacted", object["phone"])
+	}
+	if _, ok := object["firstName"]; ok {
+		t.Errorf("firstName isn't dropped")
+	}
+	if tags := object["tags"].([]interface{}); tags[0] != "a**@example.com" || tags[1] != "b**@example.com" {
+		t.Errorf("tags %v aren't redacted", tags)
+	}
+}
+
+func TestMaskNestedFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		rule   *MaskingRule
+		object map[string]interface{}
+		masked func(map[string]interface{}) interface{}
+	}{
+		{
+			name:   "field at any depth",
+			rule:   &MaskingRule{Field: "email", Policy: MaskDrop},
+			object: map[string]interface{}{"contact": map[string]interface{}{"email": "john@example.com"}},
+			masked: func(o map[string]interface{}) interface{} { return o["contact"].(map[string]interface{})["email"] },
+		},
+		{
+			name: "field in arrays of objects",
+			rule: &MaskingRule{Field: "email", Policy: MaskDrop},
+			object: map[string]interface{}{"followers": []interface{}{
+				map[string]interface{}{"email": "john@example.com"},
+			}},
+			masked: func(o map[string]interface{}) interface{} {
+				return o["followers"].([]interface{})[0].(map[string]interface{})["email"]
+			},
+		},
+		{
+			name:   "dotted path",
+			rule:   &MaskingRule{Field: "contact.name", Policy: MaskDrop},
+			object: map[string]interface{}{"name": "Deal", "contact": map[string]interface{}{"name": "John"}},
+			masked: func(o map[string]interface{}) interface{} { return o["contact"].(map[string]interface{})["name"] },
+		},
+		{
+			name:   "dotted path of a flattened column",
+			rule:   &MaskingRule{Field: "contact.name", Policy: MaskDrop},
+			object: map[string]interface{}{"name": "Deal", "contact_name": "John"},
+			masked: func(o map[string]interface{}) interface{} { return o["contact_name"] },
+		},
+		{
+			name: "dotted path of a partially flattened column",
+			rule: &MaskingRule{Field: "contact.address.city", Policy: MaskDrop},
+			object: map[string]interface{}{"name": "Deal", "contact_address": map[string]interface{}{
+				"city": "Paris",
+			}},
+			masked: func(o map[string]interface{}) interface{} {
+				return o["contact_address"].(map[string]interface{})["city"]
+			},
+		},
+		{
+			name:   "field name with the separator",
+			rule:   &MaskingRule{Field: "contact_name", Policy: MaskDrop},
+			object: map[string]interface{}{"name": "Deal", "contact_name": "John"},
+			masked: func(o map[string]interface{}) interface{} { return o["contact_name"] },
+		},
+		{
+			name:   "field name with the separator at any depth",
+			rule:   &MaskingRule{Field: "first_name", Policy: MaskDrop},
+			object: map[string]interface{}{"name": "Deal", "contact": map[string]interface{}{"first_name": "John"}},
+			masked: func(o map[string]interface{}) interface{} { return o["contact"].(map[string]interface{})["first_name"] },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mask(tt.object, []*MaskingRule{tt.rule}, "", "_")
+			if masked := tt.masked(tt.object); masked != nil {
+				t.Errorf("%s isn't masked: %v", tt.rule.Field, masked)
+			}
+			if name, ok := tt.object["name"]; ok && name != "Deal" {
+				t.Errorf("top level name %v is masked", name)
+			}
+		})
+	}
+}
+
+func TestHipaaOverrideByCollection(t *testing.T) {
+	config := &StoplightConfig{
+		HipaaMode:    true,
+		MaskingRules: []*MaskingRule{{Field: "email", Policy: MaskRedact, Collections: []string{ContactsCollection}}},
+	}
+
+	policies := func(collectionType string) []string {
+		var policies []string
+		for _, rule := range config.maskingRules(collectionType) {
+			if rule.Field == "email" {
+				policies = append(policies, rule.Policy)
+			}
+		}
+		return policies
+	}
+
+	if p := policies(ContactsCollection); len(p) != 1 || p[0] != MaskRedact {
+		t.Errorf("contacts email policies %v, expected the configured redact", p)
+	}
+	if p := policies(OpportunitiesCollection); len(p) != 1 || p[0] != MaskHash {
+		t.Errorf("opportunities email policies %v, expected the HIPAA hash", p)
+	}
+}
+
+func TestHipaaMasksOpportunityContact(t *testing.T) {
+	config := &StoplightConfig{HipaaMode: true}
+	opportunity := map[string]interface{}{
+		"name": "Deal",
+		"contact": map[string]interface{}{
+			"name":  "John Doe",
+			"email": "john@example.com",
+			"phone": "+15551234567",
+		},
+	}
+	mask(opportunity, config.maskingRules(OpportunitiesCollection), "", config.flattenSeparator())
+
+	contact := opportunity["contact"].(map[string]interface{})
+	if _, ok := contact["name"]; ok {
+		t.Errorf("contact name isn't dropped")
+	}
+	if contact["email"] != hashValue("john@example.com", "") || contact["phone"] != hashValue("+15551234567", "") {
+		t.Errorf("contact email and phone aren't hashed: %v", contact)
+	}
+	if opportunity["name"] != "Deal" {
+		t.Errorf("opportunity name is masked")
+	}
+}
+
+func TestMaskRawPayload(t *testing.T) {
+	object := map[string]interface{}{
+		"id":    "c1",
+		"email": "john@example.com",
+	}
+	addRawPayloads([]map[string]interface{}{object}, defaultRawPayloadMaxSize)
+
+	rules := []*MaskingRule{{Field: "email", Policy: MaskHash}}
+	mask(object, rules, "salt", "_")
+	maskRawPayload(object, rules, "salt", defaultRawPayloadMaxSize)
+
+	raw := object[rawField].(string)
+	if strings.Contains(raw, "john@example.com") {
+		t.Errorf("raw payload %s isn't masked", raw)
+	}
+	if !strings.Contains(raw, hashValue("john@example.com", "salt")) {
+		t.Errorf("raw payload %s doesn't have the hashed email", raw)
+	}
+}
+
+func TestFieldNameWithSeparatorIsntAPath(t *testing.T) {
+	object := map[string]interface{}{"contact": map[string]interface{}{"name": "John"}}
+	mask(object, []*MaskingRule{{Field: "contact_name", Policy: MaskDrop}}, "", "_")
+	if object["contact"].(map[string]interface{})["name"] != "John" {
+		t.Errorf("contact_name rule masked the contact.name path")
+	}
+}
+
+func TestHipaaModeMasksRawPayload(t *testing.T) {
+	s := &Stoplight{
+		config:     &StoplightConfig{HipaaMode: true, RawPayload: true, MaskingSalt: "salt"},
+		collection: &base.Collection{SourceID: "source", Type: ContactsCollection},
+	}
+	objects := []map[string]interface{}{{"id": "c1", "firstName": "John", "email": "john@example.com"}}
+	addRawPayloads(objects, defaultRawPayloadMaxSize)
+
+	objects, err := s.process(objects)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := objects[0][rawField].(string)
+	if strings.Contains(raw, "John") || strings.Contains(raw, "john@example.com") {
+		t.Errorf("raw payload %s keeps the fields masked by hipaa_mode", raw)
+	}
+}
@@ -0,0 +1,68 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"fmt"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+// GetMembershipProducts returns the membership products and courses metadata of the location. An empty list
+// is returned when memberships aren't enabled for the location
+func (s *Stoplight) GetMembershipProducts() ([]map[string]interface{}, error) {
+	products, err := s.getAllOffsets(apiURL+"/memberships/products?locationId="+s.config.LocationId, "products")
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return products, nil
+}
+
+// GetCourseOffers returns the course offers (pricing, linked products) of the location, one row per offer
+// and linked membership product with its product_id
+func (s *Stoplight) GetCourseOffers() ([]map[string]interface{}, error) {
+	offers, err := s.getAllOffsets(apiURL+"/memberships/offers?locationId="+s.config.LocationId, "offers")
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var rows []map[string]interface{}
+	for _, offer := range offers {
+		products, _ := offer["products"].([]interface{})
+		for _, product := range products {
+			row := make(map[string]interface{}, len(offer)+1)
+			for k, v := range offer {
+				if k != "products" {
+					row[k] = v
+				}
+			}
+			row["product_id"] = product
+			rows = append(rows, row)
+		}
+	}
+
+	return rows, nil
+}
+
+// GetCourseEnrollments returns the members enrollments and progress (contact, course, enrolled date, completion)
+// of the location enrolled or updated in the interval
+func (s *Stoplight) GetCourseEnrollments(interval *base.TimeInterval) ([]map[string]interface{}, error) {
+	url := fmt.Sprintf("%s/memberships/enrollments?locationId=%s&startAt=%s&endAt=%s", apiURL, s.config.LocationId,
+		interval.LowerEndpoint().Format(dateLayout), interval.UpperEndpoint().Format(dateLayout))
+	enrollments, err := s.getAllOffsets(url, "enrollments")
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return enrollments, nil
+}
@@ -0,0 +1,120 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+// GetMessages returns the conversations messages (direction, type, status, body) added in the interval.
+// Messages are streamed in real time by the InboundMessage and OutboundMessage webhooks: polling them is meant
+// for backfills and reconciliation. Conversations are requested by descending last message date, so only
+// the conversations with a message since the interval start are scanned
+func (s *Stoplight) GetMessages(interval *base.TimeInterval) ([]map[string]interface{}, error) {
+	conversations, err := s.conversationsSince(interval.LowerEndpoint())
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []map[string]interface{}
+	for _, conversation := range conversations {
+		conversationMessages, err := s.conversationMessages(fmt.Sprint(conversation["id"]), interval.LowerEndpoint())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, message := range conversationMessages {
+			added, ok := message["dateAdded"].(string)
+			t, err := time.Parse(time.RFC3339, added)
+			if !ok || err != nil || t.Before(interval.LowerEndpoint()) || !t.Before(interval.UpperEndpoint()) {
+				continue
+			}
+			messages = append(messages, message)
+		}
+	}
+
+	return messages, nil
+}
+
+// conversationsSince returns the conversations of the location with a message since the time
+func (s *Stoplight) conversationsSince(since time.Time) ([]map[string]interface{}, error) {
+	var conversations []map[string]interface{}
+	query := url.Values{
+		"locationId": {s.config.LocationId},
+		"limit":      {fmt.Sprint(pageSize)},
+		"sortBy":     {"last_message_date"},
+		"sort":       {"desc"},
+	}
+	for {
+		body, err := s.get(apiURL + "/conversations/search?" + query.Encode())
+		if err != nil {
+			return nil, err
+		}
+
+		var response struct {
+			Conversations []map[string]interface{} `json:"conversations"`
+		}
+		err = json.Unmarshal(body, &response)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, conversation := range response.Conversations {
+			lastMessageDate, _ := conversation["lastMessageDate"].(float64)
+			if time.UnixMilli(int64(lastMessageDate)).Before(since) {
+				return conversations, nil
+			}
+			conversations = append(conversations, conversation)
+		}
+
+		if len(response.Conversations) < pageSize {
+			return conversations, nil
+		}
+		last := response.Conversations[len(response.Conversations)-1]
+		query.Set("startAfterDate", fmt.Sprint(last["lastMessageDate"]))
+	}
+}
+
+// conversationMessages returns the messages of the conversation, newest first, down to the first one
+// added before the time
+func (s *Stoplight) conversationMessages(conversationId string, since time.Time) ([]map[string]interface{}, error) {
+	var messages []map[string]interface{}
+	query := url.Values{"limit": {fmt.Sprint(pageSize)}}
+	for {
+		body, err := s.get(fmt.Sprintf("%s/conversations/%s/messages?%s", apiURL, conversationId, query.Encode()))
+		if err != nil {
+			return nil, err
+		}
+
+		var response struct {
+			Messages struct {
+				Messages      []map[string]interface{} `json:"messages"`
+				LastMessageId string                   `json:"lastMessageId"`
+				NextPage      bool                     `json:"nextPage"`
+			} `json:"messages"`
+		}
+		err = json.Unmarshal(body, &response)
+		if err != nil {
+			return nil, err
+		}
+		s.keepRawPayloads(response.Messages.Messages)
+
+		for _, message := range response.Messages.Messages {
+			added, _ := message["dateAdded"].(string)
+			if t, err := time.Parse(time.RFC3339, added); err == nil && t.Before(since) {
+				return messages, nil
+			}
+			messages = append(messages, message)
+		}
+
+		if !response.Messages.NextPage || response.Messages.LastMessageId == "" {
+			return messages, nil
+		}
+		query.Set("lastMessageId", response.Messages.LastMessageId)
+	}
+}
@@ -0,0 +1,57 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+const (
+	syncedAtField   = "_synced_at"
+	syncRunIdField  = "_sync_run_id"
+	locationIdField = "_location_id"
+)
+
+// metadataFields are the _-prefixed columns added by the driver, as opposed to the record fields of the API
+// (e.g. _id)
+var metadataFields = map[string]bool{
+	syncedAtField:          true,
+	syncRunIdField:         true,
+	locationIdField:        true,
+	changeTypeField:        true,
+	idempotencyKeyField:    true,
+	qualityViolationsField: true,
+	rawField:               true,
+	surrogateKeyField:      true,
+	deletedField:           true,
+	deletedAtField:         true,
+}
+
+// newSyncRunId returns a random id identifying the objects loaded by one sync
+func newSyncRunId() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// addLocationIds stamps the objects with the location of the driver (_location_id) in multi-location mode,
+// where the records of every location are loaded into the same tables
+func (s *Stoplight) addLocationIds(objects []map[string]interface{}) {
+	if len(s.config.LocationIds) == 0 || s.config.LocationId == "" {
+		return
+	}
+	for _, object := range objects {
+		object[locationIdField] = s.config.LocationId
+	}
+}
+
+// addSyncMetadata stamps every object with the sync time (_synced_at) and the sync run id (_sync_run_id)
+func addSyncMetadata(objects []map[string]interface{}, syncedAt time.Time, syncRunId string) {
+	timestamp := syncedAt.UTC().Format(time.RFC3339)
+	for _, object := range objects {
+		object[syncedAtField] = timestamp
+		object[syncRunIdField] = syncRunId
+	}
+}
@@ -0,0 +1,142 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// toSnakeCase converts a camelCase field name to snake_case (e.g. dateAdded to date_added, contactID to contact_id)
+func toSnakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && runes[i-1] != '_' && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteRune('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// snakeCaseKeys converts every field name of the object, including nested objects ones, to snake_case. When two
+// fields are converted to the same name (e.g. firstName and first_name), the fields are processed in name order
+// and a _2, _3, ... suffix is added to the following ones
+func snakeCaseKeys(object map[string]interface{}) map[string]interface{} {
+	return convertKeys(object, toSnakeCase, snakeCaseValue)
+}
+
+func snakeCaseValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return snakeCaseKeys(v)
+	case []interface{}:
+		converted := make([]interface{}, len(v))
+		for i, element := range v {
+			converted[i] = snakeCaseValue(element)
+		}
+		return converted
+	default:
+		return value
+	}
+}
+
+// reservedWords are SQL keywords which can't be used as unquoted column names in most destinations
+var reservedWords = map[string]bool{
+	"all": true, "and": true, "as": true, "asc": true, "between": true, "by": true, "case": true, "check": true,
+	"column": true, "constraint": true, "create": true, "default": true, "delete": true, "desc": true,
+	"distinct": true, "drop": true, "else": true, "end": true, "from": true, "group": true, "having": true,
+	"in": true, "index": true, "insert": true, "into": true, "is": true, "join": true, "key": true, "like": true,
+	"limit": true, "not": true, "null": true, "on": true, "or": true, "order": true, "primary": true,
+	"references": true, "select": true, "table": true, "then": true, "to": true, "union": true, "unique": true,
+	"update": true, "user": true, "using": true, "values": true, "when": true, "where": true, "with": true,
+}
+
+// sanitizeColumnName returns a valid column identifier: characters other than letters, digits and underscores
+// are replaced with underscores, names starting with a digit are prefixed and reserved words are suffixed
+// with an underscore
+func sanitizeColumnName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_') {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+
+	sanitized := b.String()
+	if sanitized == "" || unicode.IsDigit(rune(sanitized[0])) {
+		sanitized = "_" + sanitized
+	}
+	if reservedWords[strings.ToLower(sanitized)] {
+		sanitized += "_"
+	}
+
+	return sanitized
+}
+
+// sanitizeKeys sanitizes every field name of the object, including nested objects ones. When two fields
+// are sanitized to the same name, the fields are processed in name order and a _2, _3, ... suffix is added
+// to the following ones
+func sanitizeKeys(object map[string]interface{}) map[string]interface{} {
+	return convertKeys(object, sanitizeColumnName, sanitizeValue)
+}
+
+func sanitizeValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return sanitizeKeys(v)
+	case []interface{}:
+		sanitized := make([]interface{}, len(v))
+		for i, element := range v {
+			sanitized[i] = sanitizeValue(element)
+		}
+		return sanitized
+	default:
+		return value
+	}
+}
+
+// convertKeys converts the field names and values of the object. The fields are processed in name order and
+// a _2, _3, ... suffix is added to the names already taken by a previous field
+func convertKeys(object map[string]interface{}, convertName func(string) string, convertValue func(interface{}) interface{}) map[string]interface{} {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	converted := make(map[string]interface{}, len(object))
+	for _, key := range keys {
+		name := convertName(key)
+		if _, exists := converted[name]; exists {
+			for i := 2; ; i++ {
+				candidate := fmt.Sprintf("%s_%d", name, i)
+				if _, exists := converted[candidate]; !exists {
+					name = candidate
+					break
+				}
+			}
+		}
+		converted[name] = convertValue(object[key])
+	}
+	return converted
+}
+
+// rename renames the fields of the object configured in renames for the collection type
+func (s *Stoplight) rename(object map[string]interface{}) {
+	for field, column := range s.config.Renames[s.collection.Type] {
+		if value, ok := object[field]; ok {
+			delete(object, field)
+			object[column] = value
+		}
+	}
+}
@@ -0,0 +1,50 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+func TestSnakeCaseKeysCollisions(t *testing.T) {
+	object := map[string]interface{}{
+		"first_name": "John",
+		"firstName":  "Johnny",
+		"contact":    map[string]interface{}{"dateAdded": "2023-01-01", "date_added": "2023-01-02"},
+	}
+
+	expected := map[string]interface{}{
+		"first_name":   "Johnny",
+		"first_name_2": "John",
+		"contact":      map[string]interface{}{"date_added": "2023-01-01", "date_added_2": "2023-01-02"},
+	}
+	if converted := snakeCaseKeys(object); !reflect.DeepEqual(converted, expected) {
+		t.Errorf("converted %v, expected %v", converted, expected)
+	}
+}
+
+func TestSanitizeKeysCollisions(t *testing.T) {
+	object := map[string]interface{}{"first name": "John", "first-name": "Johnny", "order": 1}
+
+	expected := map[string]interface{}{"first_name": "John", "first_name_2": "Johnny", "order_": 1}
+	if sanitized := sanitizeKeys(object); !reflect.DeepEqual(sanitized, expected) {
+		t.Errorf("sanitized %v, expected %v", sanitized, expected)
+	}
+}
+
+func TestRename(t *testing.T) {
+	s := &Stoplight{
+		config:     &StoplightConfig{Renames: map[string]map[string]string{ContactsCollection: {"dateAdded": "created_at", "source": "lead_source"}}},
+		collection: &base.Collection{SourceID: "source", Type: ContactsCollection},
+	}
+	object := map[string]interface{}{"id": "c1", "dateAdded": "2023-01-01T00:00:00Z"}
+
+	s.rename(object)
+	expected := map[string]interface{}{"id": "c1", "created_at": "2023-01-01T00:00:00Z"}
+	if !reflect.DeepEqual(object, expected) {
+		t.Errorf("renamed %v, expected %v", object, expected)
+	}
+}
@@ -0,0 +1,131 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/nyaruka/phonenumbers"
+)
+
+// normalizePhones converts the phone fields of the contacts to E.164 using the location country as default region
+// for numbers without international prefix. Numbers which can't be parsed are kept as is
+func (s *Stoplight) normalizePhones(contacts []map[string]interface{}) error {
+	location, err := s.location()
+	if err != nil {
+		return err
+	}
+	region, _ := location["country"].(string)
+
+	for _, contact := range contacts {
+		if phone, ok := contact["phone"].(string); ok {
+			contact["phone"] = toE164(phone, region)
+		}
+
+		additionalPhones, _ := contact["additionalPhones"].([]interface{})
+		for i, p := range additionalPhones {
+			if phone, ok := p.(string); ok {
+				additionalPhones[i] = toE164(phone, region)
+			}
+		}
+	}
+
+	return nil
+}
+
+// toE164 returns the phone number formatted as E.164 or the original value if it isn't a valid number
+func toE164(phone string, region string) string {
+	if phone == "" {
+		return phone
+	}
+
+	number, err := phonenumbers.Parse(phone, region)
+	if err != nil || !phonenumbers.IsValidNumber(number) {
+		return phone
+	}
+
+	return phonenumbers.Format(number, phonenumbers.E164)
+}
+
+// normalizeEmails lowercases and trims the email fields of the object (fields named email or ending with Email,
+// and additionalEmails). Plus addressing (john+tag@example.com) is stripped if stripPlus is true
+func normalizeEmails(object map[string]interface{}, stripPlus bool) {
+	for key, value := range object {
+		if !strings.HasSuffix(strings.ToLower(key), "email") && key != "additionalEmails" {
+			continue
+		}
+
+		switch v := value.(type) {
+		case string:
+			object[key] = normalizeEmail(v, stripPlus)
+		case []interface{}:
+			for i, e := range v {
+				if email, ok := e.(string); ok {
+					v[i] = normalizeEmail(email, stripPlus)
+				}
+			}
+		}
+	}
+}
+
+// normalizeEmail returns the lowercased and trimmed email, without plus addressing if stripPlus is true
+func normalizeEmail(email string, stripPlus bool) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if !stripPlus {
+		return email
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return email
+	}
+	if plus := strings.Index(email[:at], "+"); plus >= 0 {
+		email = email[:plus] + email[at:]
+	}
+
+	return email
+}
+
+// normalizeMonetaryValues converts the monetaryValue of the opportunities to a number and adds a currency column
+// with the currency configured for the location
+func (s *Stoplight) normalizeMonetaryValues(opportunities []map[string]interface{}) error {
+	currency := s.config.Currency
+	if currency == "" {
+		location, err := s.location()
+		if err != nil {
+			return err
+		}
+		currency, _ = location["currency"].(string)
+	}
+
+	for _, opportunity := range opportunities {
+		opportunity["monetaryValue"] = toNumber(opportunity["monetaryValue"])
+		opportunity["currency"] = currency
+	}
+
+	return nil
+}
+
+// toNumber converts a JSON number or a numeric string to float64, nil is returned for other values
+func toNumber(value interface{}) interface{} {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return nil
+		}
+		return f
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return nil
+		}
+		return f
+	default:
+		return nil
+	}
+}
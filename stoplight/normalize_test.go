@@ -0,0 +1,88 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+func TestNormalizePhones(t *testing.T) {
+	s := &Stoplight{
+		config:        &StoplightConfig{NormalizePhones: true},
+		collection:    &base.Collection{SourceID: "source", Type: ContactsCollection},
+		locationCache: map[string]interface{}{"country": "US"},
+	}
+	contact := map[string]interface{}{
+		"phone":            "(415) 555-2671",
+		"additionalPhones": []interface{}{"+44 20 7946 0958", "not a phone"},
+	}
+
+	err := s.normalizePhones([]map[string]interface{}{contact})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contact["phone"] != "+14155552671" {
+		t.Errorf("phone %v, expected +14155552671", contact["phone"])
+	}
+	additionalPhones := contact["additionalPhones"].([]interface{})
+	if additionalPhones[0] != "+442079460958" || additionalPhones[1] != "not a phone" {
+		t.Errorf("additional phones %v, expected the UK number in E.164 and the invalid one kept", additionalPhones)
+	}
+}
+
+func TestNormalizeEmails(t *testing.T) {
+	object := map[string]interface{}{
+		"email":            " John+Newsletter@Example.com ",
+		"additionalEmails": []interface{}{"JANE@example.com"},
+		"contactEmail":     "Sales+Leads@Example.com",
+		"name":             "John",
+	}
+
+	normalizeEmails(object, true)
+	if object["email"] != "john@example.com" || object["contactEmail"] != "sales@example.com" || object["name"] != "John" {
+		t.Errorf("normalized %v, expected lowercased emails without plus addressing", object)
+	}
+	if additionalEmails := object["additionalEmails"].([]interface{}); additionalEmails[0] != "jane@example.com" {
+		t.Errorf("additional emails %v, expected them lowercased", additionalEmails)
+	}
+
+	if email := normalizeEmail("John+Newsletter@Example.com", false); email != "john+newsletter@example.com" {
+		t.Errorf("normalized %s, expected plus addressing to be kept", email)
+	}
+}
+
+func TestNormalizeMonetaryValues(t *testing.T) {
+	s := &Stoplight{
+		config:        &StoplightConfig{},
+		collection:    &base.Collection{SourceID: "source", Type: OpportunitiesCollection},
+		locationCache: map[string]interface{}{"currency": "EUR"},
+	}
+	opportunities := []map[string]interface{}{
+		{"id": "o1", "monetaryValue": "1250.50"},
+		{"id": "o2", "monetaryValue": json.Number("300")},
+		{"id": "o3", "monetaryValue": "n/a"},
+	}
+
+	err := s.normalizeMonetaryValues(opportunities)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []interface{}{1250.5, 300.0, nil}
+	for i, opportunity := range opportunities {
+		if opportunity["monetaryValue"] != expected[i] || opportunity["currency"] != "EUR" {
+			t.Errorf("opportunity %v, expected monetaryValue %v in EUR", opportunity, expected[i])
+		}
+	}
+
+	s.config.Currency = "USD"
+	err = s.normalizeMonetaryValues(opportunities)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opportunities[0]["currency"] != "USD" {
+		t.Errorf("currency %v, expected the configured USD", opportunities[0]["currency"])
+	}
+}
@@ -0,0 +1,159 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"time"
+)
+
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+
+	partitionLayout = "2006-01-02"
+)
+
+// objectUploadFunc uploads a file to the object store
+type objectUploadFunc func(key string, body []byte, contentType string) error
+
+// partitionedLoader is the ObjectsLoader writing every load call to one file per date partition,
+// <prefix>/<collection>/date=YYYY-MM-DD/<load time>_<random id>.<format>[.gz], in an object store.
+// The partition date is the update (or creation) day of the records, the load day for records without one
+type partitionedLoader struct {
+	collection  string
+	prefix      string
+	format      string
+	compression string
+	upload      objectUploadFunc
+}
+
+func (pl *partitionedLoader) Load(objects []map[string]interface{}, pos int, total int, percent int) error {
+	if len(objects) == 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	partitions := map[string][]map[string]interface{}{}
+	for _, object := range objects {
+		t := updatedAt(object)
+		if t.IsZero() {
+			t = createdAt(object)
+		}
+		if t.IsZero() {
+			t = now
+		}
+		date := t.UTC().Format(partitionLayout)
+		partitions[date] = append(partitions[date], object)
+	}
+	dates := make([]string, 0, len(partitions))
+	for date := range partitions {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+
+	name := now.Format("20060102T150405") + "_" + newSyncRunId()[:8] + "." + pl.format
+	if pl.compression == CompressionGzip {
+		name += ".gz"
+	}
+	for _, date := range dates {
+		body, err := pl.encode(partitions[date])
+		if err != nil {
+			return err
+		}
+		key := path.Join(pl.prefix, pl.collection, "date="+date, name)
+		err = pl.upload(key, body, pl.contentType())
+		if err != nil {
+			return fmt.Errorf("Error uploading %s: %v", key, err)
+		}
+	}
+
+	return nil
+}
+
+// encode returns the file content of the objects in the loader format and compression
+func (pl *partitionedLoader) encode(objects []map[string]interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	var w io.Writer = &buffer
+	var compressed *gzip.Writer
+	if pl.compression == CompressionGzip {
+		compressed = gzip.NewWriter(&buffer)
+		w = compressed
+	}
+
+	writer, err := newRecordWriter(pl.format, w, pl.collection)
+	if err != nil {
+		return nil, err
+	}
+	err = writer.Write(objects)
+	if err != nil {
+		return nil, err
+	}
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+	if compressed != nil {
+		err = compressed.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func (pl *partitionedLoader) contentType() string {
+	if pl.compression == CompressionGzip {
+		return "application/gzip"
+	}
+	switch pl.format {
+	case FormatNdjson:
+		return "application/x-ndjson"
+	case FormatCsv:
+		return "text/csv"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// newRecordWriter returns the record writer of the format with its default options
+func newRecordWriter(format string, w io.Writer, collection string) (RecordWriter, error) {
+	switch format {
+	case FormatNdjson:
+		return NewNdjsonWriter(w), nil
+	case FormatCsv:
+		return NewCsvWriter(w, 0, ""), nil
+	case FormatParquet:
+		return NewParquetWriter(w, collection), nil
+	case FormatAvro:
+		return NewAvroWriter(w, collection), nil
+	default:
+		return nil, fmt.Errorf("format %q is not supported", format)
+	}
+}
+
+// validateObjectFormat returns an error if the format or compression of the object store loader is invalid.
+// Parquet and Avro files are already Snappy compressed
+func validateObjectFormat(store string, format string, compression string) error {
+	switch format {
+	case "", FormatNdjson, FormatCsv, FormatParquet, FormatAvro:
+	default:
+		return fmt.Errorf("Stoplight %s format %q is not supported: use ndjson, csv, parquet or avro", store, format)
+	}
+	switch compression {
+	case "", CompressionNone:
+	case CompressionGzip:
+		if format == FormatParquet || format == FormatAvro {
+			return fmt.Errorf("Stoplight %s gzip compression isn't supported for %s files, they are already compressed", store, format)
+		}
+	default:
+		return fmt.Errorf("Stoplight %s compression %q is not supported: use none or gzip", store, compression)
+	}
+	return nil
+}
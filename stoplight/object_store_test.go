@@ -0,0 +1,103 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestPartitionedLoader(t *testing.T) {
+	uploads := map[string][]byte{}
+	loader := &partitionedLoader{
+		collection:  ContactsCollection,
+		prefix:      "highlevel",
+		format:      FormatNdjson,
+		compression: CompressionGzip,
+		upload: func(key string, body []byte, contentType string) error {
+			if contentType != "application/gzip" {
+				t.Errorf("%s content type %s, expected application/gzip", key, contentType)
+			}
+			uploads[key] = body
+			return nil
+		},
+	}
+
+	err := loader.Load([]map[string]interface{}{
+		{"id": "c1", "dateUpdated": "2023-03-01T10:00:00Z"},
+		{"id": "c2", "dateAdded": "2023-03-02T10:00:00Z"},
+		{"id": "c3", "updatedAt": "2023-03-01T23:00:00Z"},
+	}, 0, 1, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := map[string]int{}
+	for key, body := range uploads {
+		if !strings.HasSuffix(key, ".ndjson.gz") {
+			t.Errorf("uploaded %s, expected a gzipped NDJSON file", key)
+		}
+		reader, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines[path.Dir(key)] = strings.Count(string(content), "\n")
+	}
+	if len(uploads) != 2 || lines["highlevel/contacts/date=2023-03-01"] != 2 || lines["highlevel/contacts/date=2023-03-02"] != 1 {
+		t.Errorf("uploaded %v lines per partition, expected 2 records on 2023-03-01 and 1 on 2023-03-02", lines)
+	}
+}
+
+func TestValidateS3LoaderConfig(t *testing.T) {
+	invalid := []*S3LoaderConfig{
+		{},
+		{Bucket: "bucket", AccessKeyId: "key"},
+		{Bucket: "bucket", Format: "xml"},
+		{Bucket: "bucket", Format: FormatParquet, Compression: CompressionGzip},
+		{Bucket: "bucket", Compression: "zstd"},
+	}
+	for _, config := range invalid {
+		if config.Validate() == nil {
+			t.Errorf("s3 loader config %+v is valid", config)
+		}
+	}
+
+	config := &S3LoaderConfig{Bucket: "bucket", Format: FormatCsv, Compression: CompressionGzip}
+	if err := config.Validate(); err != nil {
+		t.Errorf("s3 loader config %+v is invalid: %v", config, err)
+	}
+}
+
+func TestValidateGCSLoaderConfig(t *testing.T) {
+	invalid := []*GCSLoaderConfig{
+		{},
+		{Bucket: "bucket"},
+		{Bucket: "bucket", CredentialsFile: "key.json", CredentialsJson: "{}"},
+		{Bucket: "bucket", Auth: GCSAuthWorkloadIdentity, CredentialsFile: "key.json"},
+		{Bucket: "bucket", Auth: "api_key"},
+		{Bucket: "bucket", CredentialsFile: "key.json", Format: FormatAvro, Compression: CompressionGzip},
+	}
+	for _, config := range invalid {
+		if config.Validate() == nil {
+			t.Errorf("gcs loader config %+v is valid", config)
+		}
+	}
+
+	valid := []*GCSLoaderConfig{
+		{Bucket: "bucket", CredentialsFile: "key.json"},
+		{Bucket: "bucket", Auth: GCSAuthWorkloadIdentity, Format: FormatParquet},
+	}
+	for _, config := range valid {
+		if err := config.Validate(); err != nil {
+			t.Errorf("gcs loader config %+v is invalid: %v", config, err)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/xitongsys/parquet-go-source/writerfile"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+const (
+	FormatParquet = "parquet"
+
+	parquetParallelism = 4
+)
+
+// parquetWriter writes the records as a Snappy compressed Parquet file. The columns are typed from the collection
+// schema and the first written records: columns appearing later are dropped
+type parquetWriter struct {
+	w          io.Writer
+	collection string
+	writer     *writer.JSONWriter
+	columns    []*column
+}
+
+// NewParquetWriter returns a record writer writing the records of the collection as Parquet
+func NewParquetWriter(w io.Writer, collection string) RecordWriter {
+	return &parquetWriter{w: w, collection: collection}
+}
+
+func (pw *parquetWriter) Write(objects []map[string]interface{}) error {
+	if len(objects) == 0 {
+		return nil
+	}
+	if pw.writer == nil {
+		err := pw.open(objects)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, object := range objects {
+		row := make(map[string]interface{}, len(pw.columns))
+		for _, column := range pw.columns {
+			row[column.name] = columnValue(column.kind, object[column.name])
+		}
+		b, err := json.Marshal(row)
+		if err != nil {
+			return err
+		}
+		err = pw.writer.Write(string(b))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// open creates the Parquet writer with the schema of the collection and the first records
+func (pw *parquetWriter) open(objects []map[string]interface{}) error {
+	columns, err := collectionColumns(pw.collection, objects)
+	if err != nil {
+		return err
+	}
+
+	var fields []string
+	for _, column := range columns {
+		// the schema tags are comma separated key=value pairs
+		if strings.ContainsAny(column.name, ",=") {
+			logging.Warnf("%s column %q can't be written to Parquet", pw.collection, column.name)
+			continue
+		}
+		pw.columns = append(pw.columns, column)
+		fields = append(fields, fmt.Sprintf(`{"Tag": "name=%s, %s, repetitiontype=OPTIONAL"}`, column.name, parquetType(column.kind)))
+	}
+	schema := fmt.Sprintf(`{"Tag": "name=%s, repetitiontype=REQUIRED", "Fields": [%s]}`, "stoplight_"+pw.collection, strings.Join(fields, ", "))
+
+	pw.writer, err = writer.NewJSONWriter(schema, writerfile.NewWriterFile(pw.w), parquetParallelism)
+	if err != nil {
+		return fmt.Errorf("Error creating %s Parquet writer: %v", pw.collection, err)
+	}
+	pw.writer.CompressionType = parquet.CompressionCodec_SNAPPY
+
+	return nil
+}
+
+func (pw *parquetWriter) Close() error {
+	if pw.writer == nil {
+		return nil
+	}
+	return pw.writer.WriteStop()
+}
+
+// parquetType returns the Parquet type tags of the column kind
+func parquetType(kind string) string {
+	switch kind {
+	case columnNumber:
+		return "type=DOUBLE"
+	case columnInteger:
+		return "type=INT64"
+	case columnBoolean:
+		return "type=BOOLEAN"
+	default:
+		return "type=BYTE_ARRAY, convertedtype=UTF8"
+	}
+}
@@ -0,0 +1,35 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"fmt"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+// GetPaymentOrders returns the payment orders (id, contact, amount, currency, status, source) of the location
+// created in the interval
+func (s *Stoplight) GetPaymentOrders(interval *base.TimeInterval) ([]map[string]interface{}, error) {
+	url := fmt.Sprintf("%s/payments/orders?altType=location&altId=%s&startAt=%s&endAt=%s", apiURL, s.config.LocationId,
+		interval.LowerEndpoint().Format(dateLayout), interval.UpperEndpoint().Format(dateLayout))
+	return s.getAllOffsets(url, "data")
+}
+
+// GetPaymentTransactions returns the payment transactions (charge id, gateway, amount, fees, status, refunds)
+// of the location created in the interval
+func (s *Stoplight) GetPaymentTransactions(interval *base.TimeInterval) ([]map[string]interface{}, error) {
+	url := fmt.Sprintf("%s/payments/transactions?altType=location&altId=%s&startAt=%s&endAt=%s", apiURL, s.config.LocationId,
+		interval.LowerEndpoint().Format(dateLayout), interval.UpperEndpoint().Format(dateLayout))
+	return s.getAllOffsets(url, "data")
+}
+
+// GetPaymentSubscriptions returns the recurring subscriptions (plan, status, next billing date, contact) of the location
+func (s *Stoplight) GetPaymentSubscriptions() ([]map[string]interface{}, error) {
+	return s.getAllOffsets(apiURL+"/payments/subscriptions?altType=location&altId="+s.config.LocationId, "data")
+}
+
+// GetCoupons returns the coupon and promo code definitions of the location with their usageCount
+func (s *Stoplight) GetCoupons() ([]map[string]interface{}, error) {
+	return s.getAllOffsets(apiURL+"/payments/coupon/list?altType=location&altId="+s.config.LocationId, "data")
+}
@@ -0,0 +1,36 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import "fmt"
+
+// enrichPipelines denormalizes the pipeline_name and stage_name of the opportunities pipeline and stage
+// from the cached pipelines of the location
+func (s *Stoplight) enrichPipelines(opportunities []map[string]interface{}) error {
+	pipelines, err := s.pipelines()
+	if err != nil {
+		return err
+	}
+
+	pipelineNames := make(map[string]string, len(pipelines))
+	stageNames := make(map[string]string)
+	for _, pipeline := range pipelines {
+		pipelineNames[pipeline.Id] = pipeline.Name
+		for _, stage := range pipeline.Stages {
+			stageNames[stage.Id] = stage.Name
+		}
+	}
+
+	for _, opportunity := range opportunities {
+		opportunity["pipeline_name"] = nil
+		if name, ok := pipelineNames[fmt.Sprint(opportunity["pipelineId"])]; ok {
+			opportunity["pipeline_name"] = name
+		}
+		opportunity["stage_name"] = nil
+		if name, ok := stageNames[fmt.Sprint(opportunity["pipelineStageId"])]; ok {
+			opportunity["stage_name"] = name
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+func TestEnrichPipelines(t *testing.T) {
+	s := &Stoplight{
+		config:     &StoplightConfig{},
+		collection: &base.Collection{SourceID: "source", Type: OpportunitiesCollection},
+		pipelinesCache: []Pipeline{
+			{Id: "p1", Name: "Sales", Stages: []PipelineStage{{Id: "s1", Name: "Won"}}},
+		},
+	}
+	opportunities := []map[string]interface{}{
+		{"id": "o1", "pipelineId": "p1", "pipelineStageId": "s1"},
+		{"id": "o2", "pipelineId": "gone", "pipelineStageId": "gone"},
+	}
+
+	if err := s.enrichPipelines(opportunities); err != nil {
+		t.Fatal(err)
+	}
+	if opportunities[0]["pipeline_name"] != "Sales" || opportunities[0]["stage_name"] != "Won" {
+		t.Errorf("opportunity %v, expected the Sales pipeline at the Won stage", opportunities[0])
+	}
+	if opportunities[1]["pipeline_name"] != nil || opportunities[1]["stage_name"] != nil {
+		t.Errorf("opportunity %v of an unknown pipeline has names", opportunities[1])
+	}
+}
@@ -0,0 +1,91 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+// process applies the configured record transformations to the objects before they are loaded
+func (s *Stoplight) process(objects []map[string]interface{}) ([]map[string]interface{}, error) {
+	objects, err := s.filter(objects)
+	if err != nil {
+		return nil, err
+	}
+	// keyed before any transformation, so the key only depends on the API record
+	objects = s.addIdempotencyKeys(objects)
+	objects = s.checkQuality(objects)
+
+	if s.config.EmptyStringsAsNull {
+		for _, object := range objects {
+			emptyStringsToNull(object)
+		}
+	}
+
+	if len(s.config.Coercions[s.collection.Type]) > 0 {
+		for _, object := range objects {
+			err = s.coerce(object)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(s.config.Defaults[s.collection.Type]) > 0 {
+		for _, object := range objects {
+			if object[deletedField] != true {
+				s.applyDefaults(object)
+			}
+		}
+	}
+
+	if s.config.StringIds {
+		for _, object := range objects {
+			stringifyIds(object)
+		}
+	}
+
+	if s.config.NormalizeEmails {
+		for _, object := range objects {
+			normalizeEmails(object, s.config.StripEmailPlusAddressing)
+		}
+	}
+
+	if rules := s.config.maskingRules(s.collection.Type); len(rules) > 0 {
+		for _, object := range objects {
+			mask(object, rules, s.config.MaskingSalt, s.config.flattenSeparator())
+			if s.config.RawPayload {
+				maskRawPayload(object, rules, s.config.MaskingSalt, s.config.rawPayloadMaxSize())
+			}
+		}
+	}
+
+	if len(s.config.MaxLengths[s.collection.Type]) > 0 {
+		for _, object := range objects {
+			s.truncate(object)
+		}
+	}
+
+	if s.config.Flatten {
+		for i, object := range objects {
+			objects[i] = flattenObject(object, s.config.flattenSeparator(), s.config.FlattenMaxDepth)
+		}
+	}
+
+	// renamed columns are still converted by snake_case and sanitize_columns options
+	if len(s.config.Renames[s.collection.Type]) > 0 {
+		for _, object := range objects {
+			s.rename(object)
+		}
+	}
+
+	if s.config.SnakeCase {
+		for i, object := range objects {
+			objects[i] = snakeCaseKeys(object)
+		}
+	}
+
+	if s.config.SanitizeColumns {
+		for i, object := range objects {
+			objects[i] = sanitizeKeys(object)
+		}
+	}
+
+	return s.transform(objects)
+}
@@ -0,0 +1,35 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import "fmt"
+
+// GetProducts returns the products catalog (id, name, description, productType) of the location
+func (s *Stoplight) GetProducts() ([]map[string]interface{}, error) {
+	return s.getAllOffsets(apiURL+"/products/?locationId="+s.config.LocationId, "products")
+}
+
+// GetProductPrices returns the prices and variants (id, amount, currency, recurring interval) of every product
+// with their product_id
+func (s *Stoplight) GetProductPrices() ([]map[string]interface{}, error) {
+	products, err := s.GetProducts()
+	if err != nil {
+		return nil, err
+	}
+
+	var prices []map[string]interface{}
+	for _, product := range products {
+		url := fmt.Sprintf("%s/products/%v/price?locationId=%s", apiURL, product["_id"], s.config.LocationId)
+		productPrices, err := s.getAllOffsets(url, "prices")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, price := range productPrices {
+			price["product_id"] = product["_id"]
+		}
+		prices = append(prices, productPrices...)
+	}
+
+	return prices, nil
+}
@@ -0,0 +1,207 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/jitsucom/jitsu/server/logging"
+)
+
+const (
+	QualityTag        = "tag"
+	QualityQuarantine = "quarantine"
+
+	qualityViolationsField = "_quality_violations"
+)
+
+// QualityRule is a data quality rule checked on a field of the records of a collection: the field must be non-null
+// (not_null), match a regular expression (regex) and/or be one of values. Records violating the rule are tagged
+// with the rule name in _quality_violations column (tag action, default) or skipped (quarantine action)
+type QualityRule struct {
+	Name       string   `mapstructure:"name" json:"name,omitempty" yaml:"name,omitempty"`
+	Collection string   `mapstructure:"collection" json:"collection,omitempty" yaml:"collection,omitempty"`
+	Field      string   `mapstructure:"field" json:"field,omitempty" yaml:"field,omitempty"`
+	NotNull    bool     `mapstructure:"not_null" json:"not_null,omitempty" yaml:"not_null,omitempty"`
+	Regex      string   `mapstructure:"regex" json:"regex,omitempty" yaml:"regex,omitempty"`
+	Values     []string `mapstructure:"values" json:"values,omitempty" yaml:"values,omitempty"`
+	Action     string   `mapstructure:"action" json:"action,omitempty" yaml:"action,omitempty"`
+
+	compile sync.Once
+	regexp  *regexp.Regexp
+}
+
+// Validate returns an error if the quality rule is invalid
+func (qr *QualityRule) Validate() error {
+	if qr.Name == "" {
+		return errors.New("Stoplight quality rule name is required")
+	}
+
+	if qr.Collection == "" || qr.Field == "" {
+		return fmt.Errorf("Stoplight quality rule %s collection and field are required", qr.Name)
+	}
+
+	if qr.Action != "" && qr.Action != QualityTag && qr.Action != QualityQuarantine {
+		return fmt.Errorf("Stoplight quality rule %s action %q is not supported: use tag or quarantine", qr.Name, qr.Action)
+	}
+
+	if qr.Regex != "" {
+		if _, err := regexp.Compile(qr.Regex); err != nil {
+			return fmt.Errorf("Stoplight quality rule %s regex is invalid: %v", qr.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// check returns true if the record satisfies the rule
+func (qr *QualityRule) check(record map[string]interface{}) bool {
+	value, ok := record[qr.Field]
+	if !ok || value == nil {
+		return !qr.NotNull
+	}
+
+	str := fmt.Sprint(value)
+	if qr.Regex != "" {
+		qr.compile.Do(func() {
+			qr.regexp = regexp.MustCompile(qr.Regex)
+		})
+		if !qr.regexp.MatchString(str) {
+			return false
+		}
+	}
+
+	if len(qr.Values) > 0 {
+		for _, v := range qr.Values {
+			if v == str {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// QualityReport is the data quality summary of a sync: number of checked records and violations per rule.
+// It is shared by the drivers of the locations and collections of the sync and by the webhook partitions
+type QualityReport struct {
+	Checked     int            `json:"checked"`
+	Quarantined int            `json:"quarantined"`
+	Violations  map[string]int `json:"violations"`
+
+	mutex sync.Mutex
+}
+
+// newQualityReport returns an empty quality report
+func newQualityReport() *QualityReport {
+	return &QualityReport{Violations: map[string]int{}}
+}
+
+// reset empties the report at the start of a sync
+func (qr *QualityReport) reset() {
+	qr.mutex.Lock()
+	defer qr.mutex.Unlock()
+
+	qr.Checked = 0
+	qr.Quarantined = 0
+	qr.Violations = map[string]int{}
+}
+
+// add adds a checked record, its violated rules and whether it was quarantined to the report
+func (qr *QualityReport) add(violations []string, quarantined bool) {
+	if qr == nil {
+		return
+	}
+	qr.mutex.Lock()
+	defer qr.mutex.Unlock()
+
+	qr.Checked++
+	for _, rule := range violations {
+		qr.Violations[rule]++
+	}
+	if quarantined {
+		qr.Quarantined++
+	}
+}
+
+// snapshot returns a copy of the report
+func (qr *QualityReport) snapshot() *QualityReport {
+	qr.mutex.Lock()
+	defer qr.mutex.Unlock()
+
+	snapshot := &QualityReport{Checked: qr.Checked, Quarantined: qr.Quarantined, Violations: make(map[string]int, len(qr.Violations))}
+	for rule, count := range qr.Violations {
+		snapshot.Violations[rule] = count
+	}
+	return snapshot
+}
+
+func (qr *QualityReport) String() string {
+	qr.mutex.Lock()
+	defer qr.mutex.Unlock()
+
+	violations := make([]string, 0, len(qr.Violations))
+	for rule, count := range qr.Violations {
+		violations = append(violations, fmt.Sprintf("%s: %d", rule, count))
+	}
+	return fmt.Sprintf("checked: %d, quarantined: %d, violations: [%s]", qr.Checked, qr.Quarantined, strings.Join(violations, ", "))
+}
+
+// QualityReport returns a copy of the data quality summary of the last sync or nil if no quality rule is configured
+func (s *Stoplight) QualityReport() *QualityReport {
+	if len(s.config.QualityRules) == 0 || s.qualityReport == nil {
+		return nil
+	}
+	return s.qualityReport.snapshot()
+}
+
+// checkQuality checks the quality rules of the collection on the objects, tags or quarantines the violations
+// and adds them to the sync quality report
+func (s *Stoplight) checkQuality(objects []map[string]interface{}) []map[string]interface{} {
+	var rules []*QualityRule
+	for _, rule := range s.config.QualityRules {
+		if rule.Collection == s.collection.Type {
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) == 0 {
+		return objects
+	}
+
+	checked := objects[:0]
+	for _, object := range objects {
+		if object[deletedField] == true {
+			checked = append(checked, object)
+			continue
+		}
+
+		var violations []string
+		quarantined := false
+		for _, rule := range rules {
+			if rule.check(object) {
+				continue
+			}
+			violations = append(violations, rule.Name)
+			if rule.Action == QualityQuarantine {
+				quarantined = true
+			}
+		}
+
+		s.qualityReport.add(violations, quarantined)
+		if quarantined {
+			logging.Warnf("[%s] Quarantined %s object %v violating quality rules %v", s.collection.SourceID, s.collection.Type, object["id"], violations)
+			continue
+		}
+		if len(violations) > 0 {
+			object[qualityViolationsField] = strings.Join(violations, ",")
+		}
+		checked = append(checked, object)
+	}
+
+	return checked
+}
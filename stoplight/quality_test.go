@@ -0,0 +1,43 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+func TestConcurrentQualityChecks(t *testing.T) {
+	config := &StoplightConfig{QualityRules: []*QualityRule{
+		{Name: "email_required", Collection: ContactsCollection, Field: "email", NotNull: true},
+		{Name: "email_format", Collection: ContactsCollection, Field: "email", Regex: "@", Action: QualityQuarantine},
+	}}
+	s := &Stoplight{config: config, collection: &base.Collection{Type: ContactsCollection}, qualityReport: newQualityReport()}
+
+	const workers = 20
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			driver := s.forCollection(ContactsCollection)
+			driver.checkQuality([]map[string]interface{}{
+				{"id": fmt.Sprint(i), "email": "john@example.com"},
+				{"id": fmt.Sprint(i), "email": "invalid"},
+				{"id": fmt.Sprint(i)},
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	report := s.QualityReport()
+	if report.Checked != 3*workers || report.Quarantined != workers {
+		t.Errorf("checked %d and quarantined %d, expected %d and %d", report.Checked, report.Quarantined, 3*workers, workers)
+	}
+	if report.Violations["email_required"] != workers || report.Violations["email_format"] != workers {
+		t.Errorf("violations %v, expected %d per rule", report.Violations, workers)
+	}
+}
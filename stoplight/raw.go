@@ -0,0 +1,65 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+const (
+	rawField = "_raw"
+
+	defaultRawPayloadMaxSize = 65536
+)
+
+// keepRawPayloads adds the _raw column to the objects decoded from an API response when raw_payload is enabled,
+// before the collection getters pivot or enrich them
+func (s *Stoplight) keepRawPayloads(objects []map[string]interface{}) {
+	if s.config.RawPayload {
+		addRawPayloads(objects, s.config.rawPayloadMaxSize())
+	}
+}
+
+// addRawPayloads adds the _raw column with the JSON of the untouched API payload to the objects which don't have
+// one yet, so fields which aren't mapped can be recovered without re-syncing. Payloads larger than maxSize bytes
+// are replaced by null
+func addRawPayloads(objects []map[string]interface{}, maxSize int) {
+	for _, object := range objects {
+		if _, ok := object[rawField]; ok {
+			continue
+		}
+		b, err := json.Marshal(object)
+		if err != nil || len(b) > maxSize {
+			object[rawField] = nil
+			continue
+		}
+		object[rawField] = string(b)
+	}
+}
+
+// maskRawPayload applies the masking rules to the _raw payload of the object, so the masked fields don't reach
+// the destination through it. Payloads which can't be masked are replaced by null
+func maskRawPayload(object map[string]interface{}, rules []*MaskingRule, salt string, maxSize int) {
+	raw, ok := object[rawField].(string)
+	if !ok {
+		return
+	}
+
+	payload := map[string]interface{}{}
+	decoder := json.NewDecoder(bytes.NewReader([]byte(raw)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&payload); err != nil {
+		object[rawField] = nil
+		return
+	}
+	// the raw payload isn't flattened
+	mask(payload, rules, salt, "")
+
+	b, err := json.Marshal(payload)
+	if err != nil || len(b) > maxSize {
+		object[rawField] = nil
+		return
+	}
+	object[rawField] = string(b)
+}
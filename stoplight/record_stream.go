@@ -0,0 +1,135 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"errors"
+	"sync"
+)
+
+const subscriptionBufferSize = 1000
+
+var errSlowSubscriber = errors.New("subscriber is too slow: records buffer is full")
+
+// StreamRecord is a record loaded by a driver (polled or from a webhook) with its source and collection
+type StreamRecord struct {
+	SourceId   string
+	Collection string
+	Record     map[string]interface{}
+}
+
+// RecordStream fans the loaded records out to its subscribers
+type RecordStream struct {
+	mutex         sync.Mutex
+	subscriptions map[*Subscription]bool
+}
+
+// Subscription receives the records of the subscribed collections of a RecordStream. Subscribers which don't
+// keep up are closed with an error rather than slowing the drivers down
+type Subscription struct {
+	stream      *RecordStream
+	collections map[string]bool
+	records     chan *StreamRecord
+	err         error
+}
+
+var (
+	recordStreamsMutex sync.RWMutex
+	recordStreams      []*RecordStream
+)
+
+// NewRecordStream returns a record stream without subscribers
+func NewRecordStream() *RecordStream {
+	return &RecordStream{subscriptions: map[*Subscription]bool{}}
+}
+
+// RegisterRecordStream registers a record stream receiving the records loaded by every Stoplight driver instance
+func RegisterRecordStream(stream *RecordStream) {
+	recordStreamsMutex.Lock()
+	defer recordStreamsMutex.Unlock()
+
+	recordStreams = append(recordStreams, stream)
+}
+
+// publish publishes the loaded objects of the collection to the registered record streams
+func (s *Stoplight) publish(collection string, objects []map[string]interface{}) {
+	recordStreamsMutex.RLock()
+	defer recordStreamsMutex.RUnlock()
+
+	for _, stream := range recordStreams {
+		stream.Publish(s.collection.SourceID, collection, objects)
+	}
+}
+
+// Subscribe returns a subscription to the records of the collections, or of every collection if none is given
+func (rs *RecordStream) Subscribe(collections ...string) *Subscription {
+	subscription := &Subscription{
+		stream:      rs,
+		collections: make(map[string]bool, len(collections)),
+		records:     make(chan *StreamRecord, subscriptionBufferSize),
+	}
+	for _, collection := range collections {
+		subscription.collections[collection] = true
+	}
+
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	rs.subscriptions[subscription] = true
+	return subscription
+}
+
+// Publish sends the objects of the collection to the subscriptions of the collection
+func (rs *RecordStream) Publish(sourceId string, collection string, objects []map[string]interface{}) {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	for subscription := range rs.subscriptions {
+		if len(subscription.collections) > 0 && !subscription.collections[collection] {
+			continue
+		}
+
+		for _, object := range objects {
+			select {
+			case subscription.records <- &StreamRecord{SourceId: sourceId, Collection: collection, Record: object}:
+			default:
+				rs.unsubscribe(subscription, errSlowSubscriber)
+			}
+			if subscription.err != nil {
+				break
+			}
+		}
+	}
+}
+
+// unsubscribe removes the subscription and closes its records channel. Must be called with the mutex locked
+func (rs *RecordStream) unsubscribe(subscription *Subscription, err error) {
+	if !rs.subscriptions[subscription] {
+		return
+	}
+
+	delete(rs.subscriptions, subscription)
+	subscription.err = err
+	close(subscription.records)
+}
+
+// Records returns the channel of the records of the subscription, closed when the subscription is closed
+func (sub *Subscription) Records() <-chan *StreamRecord {
+	return sub.records
+}
+
+// Err returns the error which closed the subscription or nil
+func (sub *Subscription) Err() error {
+	sub.stream.mutex.Lock()
+	defer sub.stream.mutex.Unlock()
+
+	return sub.err
+}
+
+// Close closes the subscription
+func (sub *Subscription) Close() {
+	sub.stream.mutex.Lock()
+	defer sub.stream.mutex.Unlock()
+
+	sub.stream.unsubscribe(sub, nil)
+}
@@ -0,0 +1,25 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"fmt"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+// GetReviews returns the reviews (rating, source, contact, response status) collected by the reputation module
+// in the interval. An empty list is returned when the reputation module isn't enabled for the location
+func (s *Stoplight) GetReviews(interval *base.TimeInterval) ([]map[string]interface{}, error) {
+	url := fmt.Sprintf("%s/locations/%s/reviews?startAt=%s&endAt=%s", apiURL, s.config.LocationId,
+		interval.LowerEndpoint().Format(dateLayout), interval.UpperEndpoint().Format(dateLayout))
+	reviews, err := s.getAllOffsets(url, "reviews")
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return reviews, nil
+}
@@ -0,0 +1,86 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+// S3LoaderConfig is the configuration of the S3 loader. Without access key the default AWS credentials chain
+// (environment, shared config, instance role) is used. Endpoint is set for S3 compatible stores (path style).
+// Format is ndjson (default), csv, parquet or avro and compression none (default) or gzip
+type S3LoaderConfig struct {
+	Bucket          string `mapstructure:"bucket" json:"bucket,omitempty" yaml:"bucket,omitempty"`
+	Prefix          string `mapstructure:"prefix" json:"prefix,omitempty" yaml:"prefix,omitempty"`
+	Region          string `mapstructure:"region" json:"region,omitempty" yaml:"region,omitempty"`
+	Endpoint        string `mapstructure:"endpoint" json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	AccessKeyId     string `mapstructure:"access_key_id" json:"access_key_id,omitempty" yaml:"access_key_id,omitempty"`
+	SecretAccessKey string `mapstructure:"secret_access_key" json:"secret_access_key,omitempty" yaml:"secret_access_key,omitempty"`
+	Format          string `mapstructure:"format" json:"format,omitempty" yaml:"format,omitempty"`
+	Compression     string `mapstructure:"compression" json:"compression,omitempty" yaml:"compression,omitempty"`
+}
+
+// Validate returns an error if the S3 loader configuration is invalid
+func (sc *S3LoaderConfig) Validate() error {
+	if sc.Bucket == "" {
+		return errors.New("Stoplight s3 bucket is required")
+	}
+	if (sc.AccessKeyId == "") != (sc.SecretAccessKey == "") {
+		return errors.New("Stoplight s3 access_key_id and secret_access_key must be set together")
+	}
+	return validateObjectFormat("s3", sc.Format, sc.Compression)
+}
+
+// NewS3Loader returns the ObjectsLoader writing the loaded objects of the collection to S3, partitioned by day
+// under <prefix>/<collection>/date=YYYY-MM-DD/
+func NewS3Loader(config *S3LoaderConfig, collection string) (base.ObjectsLoader, error) {
+	err := config.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	awsConfig := &aws.Config{}
+	if config.Region != "" {
+		awsConfig.Region = aws.String(config.Region)
+	}
+	if config.Endpoint != "" {
+		awsConfig.Endpoint = aws.String(config.Endpoint)
+		awsConfig.S3ForcePathStyle = aws.Bool(true)
+	}
+	if config.AccessKeyId != "" {
+		awsConfig.Credentials = credentials.NewStaticCredentials(config.AccessKeyId, config.SecretAccessKey, "")
+	}
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, err
+	}
+	uploader := s3manager.NewUploader(sess)
+
+	format := config.Format
+	if format == "" {
+		format = FormatNdjson
+	}
+	return &partitionedLoader{
+		collection:  collection,
+		prefix:      strings.Trim(config.Prefix, "/"),
+		format:      format,
+		compression: config.Compression,
+		upload: func(key string, body []byte, contentType string) error {
+			_, err := uploader.Upload(&s3manager.UploadInput{
+				Bucket:      aws.String(config.Bucket),
+				Key:         aws.String(key),
+				Body:        bytes.NewReader(body),
+				ContentType: aws.String(contentType),
+			})
+			return err
+		},
+	}, nil
+}
@@ -0,0 +1,89 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+const (
+	eventNew = iota
+	eventInFlight
+	eventSeen
+)
+
+// seenEventIds is a bounded cache of the webhook event ids handled within a ttl, with the ids of the events
+// being handled. The oldest ids are evicted first
+type seenEventIds struct {
+	mutex    sync.Mutex
+	ttl      time.Duration
+	size     int
+	order    *list.List
+	seenAts  map[string]*list.Element
+	inFlight map[string]bool
+}
+
+// seenEventId is an id of the cache with the time it was seen
+type seenEventId struct {
+	id     string
+	seenAt time.Time
+}
+
+// newSeenEventIds returns a cache remembering at most size ids for ttl
+func newSeenEventIds(ttl time.Duration, size int) *seenEventIds {
+	return &seenEventIds{
+		ttl:      ttl,
+		size:     size,
+		order:    list.New(),
+		seenAts:  make(map[string]*list.Element),
+		inFlight: make(map[string]bool),
+	}
+}
+
+// start returns eventSeen if the event id was handled within the ttl, eventInFlight if the event is being
+// handled or eventNew after marking it in flight. The handling of new events must be finished with finish
+func (c *seenEventIds) start(id string, now time.Time) int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// expired ids are at the front
+	for front := c.order.Front(); front != nil && now.Sub(front.Value.(*seenEventId).seenAt) > c.ttl; front = c.order.Front() {
+		c.order.Remove(front)
+		delete(c.seenAts, front.Value.(*seenEventId).id)
+	}
+
+	if _, ok := c.seenAts[id]; ok {
+		return eventSeen
+	}
+	if c.inFlight[id] {
+		return eventInFlight
+	}
+
+	c.inFlight[id] = true
+	return eventNew
+}
+
+// finish ends the handling of the event id and remembers it if it was handled, so redeliveries of failed
+// events are handled again
+func (c *seenEventIds) finish(id string, handled bool, now time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.inFlight, id)
+	if !handled {
+		return
+	}
+	if element, ok := c.seenAts[id]; ok {
+		c.order.Remove(element)
+	}
+
+	c.seenAts[id] = c.order.PushBack(&seenEventId{id: id, seenAt: now})
+	for c.order.Len() > c.size {
+		front := c.order.Front()
+		c.order.Remove(front)
+		delete(c.seenAts, front.Value.(*seenEventId).id)
+	}
+
+}
@@ -0,0 +1,47 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+// GetSocialAccounts returns the social accounts (platform, name, status) connected to the social planner of the location
+func (s *Stoplight) GetSocialAccounts() ([]map[string]interface{}, error) {
+	return s.getObjects(fmt.Sprintf("%s/social-media-posting/%s/accounts", apiURL, s.config.LocationId), "results.accounts")
+}
+
+// GetSocialPosts returns the scheduled and published social posts (content, platforms, status, publish time)
+// of the location in the interval
+func (s *Stoplight) GetSocialPosts(interval *base.TimeInterval) ([]map[string]interface{}, error) {
+	url := fmt.Sprintf("%s/social-media-posting/%s/posts/list", apiURL, s.config.LocationId)
+
+	var posts []map[string]interface{}
+	for skip := 0; ; skip += pageSize {
+		body, err := s.post(url, map[string]interface{}{
+			"type":     "all",
+			"fromDate": interval.LowerEndpoint().Format(time.RFC3339),
+			"toDate":   interval.UpperEndpoint().Format(time.RFC3339),
+			"skip":     strconv.Itoa(skip),
+			"limit":    strconv.Itoa(pageSize),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		pagePosts, err := parseObjects(body, "results.posts")
+		if err != nil {
+			return nil, err
+		}
+		s.keepRawPayloads(pagePosts)
+		posts = append(posts, pagePosts...)
+
+		if len(pagePosts) < pageSize {
+			return posts, nil
+		}
+	}
+}
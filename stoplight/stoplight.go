@@ -3,18 +3,105 @@
 package stoplight
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+	"github.com/jitsucom/jitsu/server/jsonutils"
+	"github.com/jitsucom/jitsu/server/logging"
+)
+
+const (
+	apiURL = "https://services.leadconnectorhq.com"
+
+	pageSize            = 100
+	maxRateLimitRetries = 5
+	defaultRetryAfter   = 10 * time.Second
+
+	CalendarsCollection            = "calendars"
+	ContactsCollection             = "contacts"
+	OpportunitiesCollection        = "opportunities"
+	CustomFieldsCollection         = "custom_fields"
+	CustomValuesCollection         = "custom_values"
+	ContactCustomFieldsCollection  = "contact_custom_fields"
+	ContactTagsCollection          = "contact_tags"
+	ContactAppointmentsCollection  = "contact_appointments"
+	ContactAttributionsCollection  = "contact_attributions"
+	FormsCollection                = "forms"
+	FormSubmissionsCollection      = "form_submissions"
+	SurveysCollection              = "surveys"
+	SurveySubmissionsCollection    = "survey_submissions"
+	WorkflowsCollection            = "workflows"
+	CampaignsCollection            = "campaigns"
+	EmailTemplatesCollection       = "email_templates"
+	SmsTemplatesCollection         = "sms_templates"
+	TriggerLinksCollection         = "trigger_links"
+	TriggerLinkClicksCollection    = "trigger_link_clicks"
+	MediaFilesCollection           = "media_files"
+	InvoicesCollection             = "invoices"
+	InvoiceItemsCollection         = "invoice_items"
+	InvoiceTemplatesCollection     = "invoice_templates"
+	EstimatesCollection            = "estimates"
+	PaymentOrdersCollection        = "payment_orders"
+	PaymentTransactionsCollection  = "payment_transactions"
+	PaymentSubscriptionsCollection = "payment_subscriptions"
+	ProductsCollection             = "products"
+	ProductPricesCollection        = "product_prices"
+	CouponsCollection              = "coupons"
+	FunnelsCollection              = "funnels"
+	FunnelPagesCollection          = "funnel_pages"
+	FunnelStatsCollection          = "funnel_stats"
+	BlogsCollection                = "blogs"
+	BlogPostsCollection            = "blog_posts"
+	SocialAccountsCollection       = "social_accounts"
+	SocialPostsCollection          = "social_posts"
+	ReviewsCollection              = "reviews"
+	MembershipProductsCollection   = "membership_products"
+	CourseOffersCollection         = "course_offers"
+	CourseEnrollmentsCollection    = "course_enrollments"
+	ObjectSchemasCollection        = "object_schemas"
+	AssociationsCollection         = "associations"
+	SaasSubscriptionsCollection    = "saas_subscriptions"
+	SnapshotsCollection            = "snapshots"
+	AppointmentNotesCollection     = "appointment_notes"
+	ContactDndSettingsCollection   = "contact_dnd_settings"
+	DuplicateContactsCollection    = "duplicate_contacts"
+	AuditLogsCollection            = "audit_logs"
+	PhoneNumbersCollection         = "phone_numbers"
+	UrlRedirectsCollection         = "url_redirects"
+	MessagesCollection             = "messages"
 )
 
 type Stoplight struct {
 	client *http.Client
 	ctx    context.Context
+	config *StoplightConfig
 
 	collection *base.Collection
+
+	transformers  []Transformer
+	qualityReport *QualityReport
+	lastSyncStats *lastSyncStats
+
+	calendarsCache map[string]map[string]interface{}
+	locationCache  map[string]interface{}
+	pipelinesCache []Pipeline
+	usersCache     map[string]User
+
+	pendingVersions *pendingVersions
+	pendingSeenIds  *pendingSeenIds
+	backfill        *backfillRange
+	webhookDrivers  *webhookDrivers
+	quarantined     int64
 }
 
 func init() {
@@ -32,11 +119,29 @@ func NewStoplight(ctx context.Context, sourceConfig *base.SourceConfig, collecti
 
 	client := &http.Client{}
 
-	return &Stoplight{
-		client: client,
-		ctx:    ctx,
-		collection: collection,
-	}, nil
+	s := &Stoplight{
+		client:         client,
+		ctx:            ctx,
+		config:         config,
+		collection:     collection,
+		qualityReport:  newQualityReport(),
+		lastSyncStats:  &lastSyncStats{},
+		webhookDrivers: &webhookDrivers{drivers: map[string]*webhookDriver{}},
+	}
+
+	if len(config.JsTransforms) > 0 {
+		timeout, err := config.jsTransformTimeout()
+		if err != nil {
+			return nil, err
+		}
+		transformer, err := newJsTransformer(config.JsTransforms, timeout, config.JsTransformMaxMemory)
+		if err != nil {
+			return nil, err
+		}
+		s.AddTransformer(transformer)
+	}
+
+	return s, nil
 }
 
 // TestStoplight tests connection to Stoplight without creating Driver instance
@@ -49,7 +154,7 @@ func TestStoplight(sourceConfig *base.SourceConfig) error {
 
 	client := &http.Client{}
 
-	req, err := http.NewRequest("GET", fmt.Sprintf("https://services.leadconnectorhq.com/calendars/%s", config.CalendarId), nil)
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/locations/%s", apiURL, config.LocationId), nil)
 	if err != nil {
 		return err
 	}
@@ -71,13 +176,13 @@ func TestStoplight(sourceConfig *base.SourceConfig) error {
 		return err
 	}
 
-	var calendar map[string]interface{}
-	err = json.Unmarshal(body, &calendar)
+	var location map[string]interface{}
+	err = json.Unmarshal(body, &location)
 	if err != nil {
 		return err
 	}
 
-	if calendar == nil {
+	if location == nil {
 		return fmt.Errorf("Stoplight returned empty response")
 	}
 
@@ -85,7 +190,11 @@ func TestStoplight(sourceConfig *base.SourceConfig) error {
 }
 
 func (s *Stoplight) GetCollectionTable() string {
-	return s.collection.GetTableName()
+	table := s.collection.GetTableName()
+	if strings.HasPrefix(table, customObjectsPrefix) {
+		return customObjectTable(table)
+	}
+	return table
 }
 
 func (s *Stoplight) GetCollectionMetaKey() string {
@@ -101,130 +210,582 @@ func (s *Stoplight) ReplaceTables() bool {
 }
 
 func (s *Stoplight) GetObjectsFor(interval *base.TimeInterval, objectsLoader base.ObjectsLoader) error {
-	// Get the calendars for the given interval.
-	calendars, err := s.GetCalendars()
-	if err != nil {
-		return err
+	syncedAt := time.Now()
+	syncRunId := newSyncRunId()
+	if s.qualityReport == nil {
+		s.qualityReport = newQualityReport()
+	}
+	s.qualityReport.reset()
+	stats := &SyncStats{SyncRunId: syncRunId, SyncedAt: syncedAt.UTC()}
+
+	locations := s.locations()
+	for i, location := range locations {
+		objects, err := location.extract(interval)
+		if err != nil {
+			return err
+		}
+
+		location.addLocationIds(objects)
+		addSyncMetadata(objects, syncedAt, syncRunId)
+
+		// Load the objects into the database.
+		err = objectsLoader.Load(objects, i, len(locations), (i+1)*100/len(locations))
+		if err != nil {
+			return err
+		}
+
+		err = location.commitLoadedVersions()
+		if err != nil {
+			return err
+		}
+		err = location.commitSeenIds()
+		if err != nil {
+			return err
+		}
+		s.publish(s.collection.Type, objects)
+		stats.count(objects)
+		stats.Quarantined += int(atomic.LoadInt64(&location.quarantined))
 	}
 
-	// Get the contacts for the given interval.
-	contacts, err := s.GetContacts()
+	stats.Quality = s.QualityReport()
+	s.setSyncStats(stats)
+	logging.Infof("[%s] %s sync stats: %s", s.collection.SourceID, s.collection.Type, stats)
+
+	return nil
+}
+
+// extract returns the processed objects of the driver collection in the interval
+func (s *Stoplight) extract(interval *base.TimeInterval) ([]map[string]interface{}, error) {
+	atomic.StoreInt64(&s.quarantined, 0)
+	objects, err := s.getCollectionObjects(s.collection.Type, interval)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if s.config.RawPayload {
+		// rows derived from the API payloads (contact tags...) don't have a raw payload yet
+		addRawPayloads(objects, s.config.rawPayloadMaxSize())
+	}
+	// ids of the full scan, including the objects quarantined by the validation
+	var scannedIds []string
+	if s.config.DeletionDetection {
+		scannedIds = objectIds(objects)
 	}
 
-	// Get the opportunities for the given interval.
-	opportunities, err := s.GetOpportunities()
-	if err != nil {
-		return err
+	objects = s.checkTypes(s.collection.Type, objects)
+	if s.config.SchemaValidation != "" {
+		objects, err = s.validate(s.collection.Type, objects)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// child rows sharing their parent id are deduped on their surrogate key
+	s.addSurrogateKeys(objects)
+	objects = dedupe(objects)
+	if s.backfill != nil && !s.backfill.byInterval {
+		objects = s.backfill.filter(objects)
+	}
+
+	// backfills don't touch the cursors of the regular syncs
+	if s.backfill == nil && s.config.DeletionDetection && (s.collection.Type == ContactsCollection || s.collection.Type == OpportunitiesCollection) {
+		tombstones, err := s.detectDeletions(objects, scannedIds)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, tombstones...)
+	}
+	inferChangeTypes(objects, interval)
+
+	// backfills reload the records already loaded by the webhooks
+	if s.backfill == nil && s.hybrid() {
+		objects, s.pendingVersions, err = s.skipLoadedVersions(objects, s.config.LocationId)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// Load the objects into the database.
-	return objectsLoader.Load(interval, calendars, contacts, opportunities)
+	return s.process(objects)
 }
 
-func (s *Stoplight) GetCalendars() error {
-	// Get the calendars from the API endpoint.
-	req, err := http.NewRequest("GET", "https://services.leadconnectorhq.com/calendars/", nil)
-	if err != nil {
-		return err
+// locations returns a driver per location to sync: the driver itself in single location mode or a copy
+// per location of location_ids in multi-location mode
+func (s *Stoplight) locations() []*Stoplight {
+	if len(s.config.LocationIds) == 0 {
+		return []*Stoplight{s}
 	}
 
-	req.Header.Add("Authorization", s.config.AccessToken)
-	req.Header.Add("Version", s.config.ApiVersion)
+	locations := make([]*Stoplight, 0, len(s.config.LocationIds))
+	for _, locationId := range s.config.LocationIds {
+		config := *s.config
+		config.LocationId = locationId
+		locations = append(locations, &Stoplight{
+			client:        s.client,
+			ctx:           s.ctx,
+			config:        &config,
+			collection:    s.collection,
+			transformers:  s.transformers,
+			qualityReport: s.qualityReport,
+		})
+	}
 
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return err
+	return locations
+}
+
+// forCollection returns a copy of the driver for another collection type of the same source
+func (s *Stoplight) forCollection(collectionType string) *Stoplight {
+	if s.collection.Type == collectionType {
+		return s
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("Stoplight returned status code %d", resp.StatusCode)
+	collection := *s.collection
+	collection.Type = collectionType
+	return &Stoplight{
+		client:        s.client,
+		ctx:           s.ctx,
+		config:        s.config,
+		collection:    &collection,
+		transformers:  s.transformers,
+		qualityReport: s.qualityReport,
 	}
+}
 
-	body, err := ioutil.ReadAll(resp.Body)
+// getCollectionObjects returns the objects of the collection type in the interval
+func (s *Stoplight) getCollectionObjects(collectionType string, interval *base.TimeInterval) ([]map[string]interface{}, error) {
+	switch collectionType {
+	case CalendarsCollection:
+		return s.GetCalendars()
+	case ContactsCollection:
+		return s.GetContacts()
+	case OpportunitiesCollection:
+		return s.GetOpportunities()
+	case CustomFieldsCollection:
+		return s.GetCustomFields()
+	case CustomValuesCollection:
+		return s.GetCustomValues()
+	case ContactCustomFieldsCollection:
+		return s.GetContactCustomFields()
+	case ContactTagsCollection:
+		return s.GetContactTags()
+	case ContactAppointmentsCollection:
+		return s.GetContactAppointments()
+	case ContactAttributionsCollection:
+		return s.GetContactAttributions()
+	case FormsCollection:
+		return s.GetForms()
+	case FormSubmissionsCollection:
+		return s.GetFormSubmissions(interval)
+	case SurveysCollection:
+		return s.GetSurveys()
+	case SurveySubmissionsCollection:
+		return s.GetSurveySubmissions(interval)
+	case WorkflowsCollection:
+		return s.GetWorkflows()
+	case CampaignsCollection:
+		return s.GetCampaigns()
+	case EmailTemplatesCollection:
+		return s.GetEmailTemplates()
+	case SmsTemplatesCollection:
+		return s.GetSmsTemplates()
+	case TriggerLinksCollection:
+		return s.GetTriggerLinks()
+	case TriggerLinkClicksCollection:
+		return s.GetTriggerLinkClicks(interval)
+	case MediaFilesCollection:
+		return s.GetMediaFiles()
+	case InvoicesCollection:
+		return s.GetInvoices(interval)
+	case InvoiceItemsCollection:
+		return s.GetInvoiceItems(interval)
+	case InvoiceTemplatesCollection:
+		return s.GetInvoiceTemplates()
+	case EstimatesCollection:
+		return s.GetEstimates(interval)
+	case PaymentOrdersCollection:
+		return s.GetPaymentOrders(interval)
+	case PaymentTransactionsCollection:
+		return s.GetPaymentTransactions(interval)
+	case PaymentSubscriptionsCollection:
+		return s.GetPaymentSubscriptions()
+	case ProductsCollection:
+		return s.GetProducts()
+	case ProductPricesCollection:
+		return s.GetProductPrices()
+	case CouponsCollection:
+		return s.GetCoupons()
+	case FunnelsCollection:
+		return s.GetFunnels()
+	case FunnelPagesCollection:
+		return s.GetFunnelPages()
+	case FunnelStatsCollection:
+		return s.GetFunnelStats(interval)
+	case BlogsCollection:
+		return s.GetBlogs()
+	case BlogPostsCollection:
+		return s.GetBlogPosts()
+	case SocialAccountsCollection:
+		return s.GetSocialAccounts()
+	case SocialPostsCollection:
+		return s.GetSocialPosts(interval)
+	case ReviewsCollection:
+		return s.GetReviews(interval)
+	case MembershipProductsCollection:
+		return s.GetMembershipProducts()
+	case CourseOffersCollection:
+		return s.GetCourseOffers()
+	case CourseEnrollmentsCollection:
+		return s.GetCourseEnrollments(interval)
+	case ObjectSchemasCollection:
+		return s.GetObjectSchemas()
+	case AssociationsCollection:
+		return s.GetAssociations()
+	case SaasSubscriptionsCollection:
+		return s.GetSaasSubscriptions()
+	case SnapshotsCollection:
+		return s.GetSnapshots()
+	case AppointmentNotesCollection:
+		return s.GetAppointmentNotes(interval)
+	case ContactDndSettingsCollection:
+		return s.GetContactDndSettings()
+	case DuplicateContactsCollection:
+		return s.GetDuplicateContacts()
+	case AuditLogsCollection:
+		return s.GetAuditLogs(interval)
+	case PhoneNumbersCollection:
+		return s.GetPhoneNumbers()
+	case UrlRedirectsCollection:
+		return s.GetUrlRedirects()
+	case MessagesCollection:
+		return s.GetMessages(interval)
+	}
+
+	if explosion := s.config.arrayExplosion(collectionType); explosion != nil {
+		return s.explodeArray(explosion, interval)
+	}
+
+	if strings.HasPrefix(collectionType, customObjectsPrefix) {
+		return s.GetCustomObjectRecords(collectionType)
+	}
+
+	return nil, fmt.Errorf("Stoplight collection %s is not supported", collectionType)
+}
+
+func (s *Stoplight) GetCalendars() ([]map[string]interface{}, error) {
+	return s.getObjects(apiURL+"/calendars/", "calendars")
+}
+
+func (s *Stoplight) GetContacts() ([]map[string]interface{}, error) {
+	contacts, err := s.listContacts()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Parse the JSON response.
-	var calendars []map[string]interface{}
-	err = json.Unmarshal(body, &calendars)
+	err = s.shapeContacts(contacts)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	return contacts, nil
+}
+
+// shapeContacts applies the post-fetch steps of the contacts: custom fields pivot, phones normalization and
+// email verification extraction
+func (s *Stoplight) shapeContacts(contacts []map[string]interface{}) error {
+	if s.config.PivotCustomFields {
+		err := s.pivotCustomFields(contacts)
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.config.NormalizePhones {
+		err := s.normalizePhones(contacts)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, contact := range contacts {
+		extractEmailVerification(contact)
 	}
 
-	// Return the calendars.
 	return nil
 }
 
-func (s *Stoplight) GetContacts() error {
-	// Get the contacts from the API endpoint.
-	req, err := http.NewRequest("GET", "https://services.leadconnectorhq.com/contacts/", nil)
+func (s *Stoplight) GetOpportunities() ([]map[string]interface{}, error) {
+	opportunities, err := s.getAllPages(apiURL+"/opportunities/search?location_id="+s.config.LocationId, "opportunities")
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	req.Header.Add("Authorization", s.config.AccessToken)
-	req.Header.Add("Version", s.config.ApiVersion)
+	err = s.shapeOpportunities(opportunities)
+	if err != nil {
+		return nil, err
+	}
 
-	resp, err := s.client.Do(req)
+	return opportunities, nil
+}
+
+// shapeOpportunities applies the post-fetch steps of the opportunities: monetary values normalization and
+// pipelines and users enrichment
+func (s *Stoplight) shapeOpportunities(opportunities []map[string]interface{}) error {
+	err := s.normalizeMonetaryValues(opportunities)
 	if err != nil {
 		return err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("Stoplight returned status code %d", resp.StatusCode)
+	if s.config.EnrichPipelines {
+		err = s.enrichPipelines(opportunities)
+		if err != nil {
+			return err
+		}
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
+	if s.config.EnrichUsers {
+		err = s.enrichUsers(opportunities, "assignedTo", "assigned_user")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetCustomFields returns the custom field definitions (id, name, dataType, model, picklistOptions)
+// of the location, used to interpret the customFields arrays of contacts and opportunities
+func (s *Stoplight) GetCustomFields() ([]map[string]interface{}, error) {
+	return s.getObjects(fmt.Sprintf("%s/locations/%s/customFields", apiURL, s.config.LocationId), "customFields")
+}
+
+// GetCustomValues returns the custom values (key/value settings used in workflows and templates) of the location
+func (s *Stoplight) GetCustomValues() ([]map[string]interface{}, error) {
+	return s.getObjects(fmt.Sprintf("%s/locations/%s/customValues", apiURL, s.config.LocationId), "customValues")
+}
+
+// GetForms returns the form definitions (id, name, fields) of the location
+func (s *Stoplight) GetForms() ([]map[string]interface{}, error) {
+	return s.getObjects(apiURL+"/forms/?locationId="+s.config.LocationId, "forms")
+}
+
+// GetSurveys returns the survey definitions of the location
+func (s *Stoplight) GetSurveys() ([]map[string]interface{}, error) {
+	return s.getObjects(apiURL+"/surveys/?locationId="+s.config.LocationId, "surveys")
+}
+
+// GetWorkflows returns the workflows metadata (id, name, status, createdAt, updatedAt) of the location
+func (s *Stoplight) GetWorkflows() ([]map[string]interface{}, error) {
+	return s.getObjects(apiURL+"/workflows/?locationId="+s.config.LocationId, "workflows")
+}
+
+// GetCampaigns returns the campaigns (legacy campaigns endpoint) of the location with their status
+func (s *Stoplight) GetCampaigns() ([]map[string]interface{}, error) {
+	return s.getObjects(apiURL+"/campaigns/?locationId="+s.config.LocationId, "campaigns")
+}
+
+// GetEmailTemplates returns the email templates metadata (id, name, updatedAt) of the location
+func (s *Stoplight) GetEmailTemplates() ([]map[string]interface{}, error) {
+	return s.getObjects(fmt.Sprintf("%s/locations/%s/templates?type=email", apiURL, s.config.LocationId), "templates")
+}
+
+// GetSmsTemplates returns the SMS templates metadata of the location
+func (s *Stoplight) GetSmsTemplates() ([]map[string]interface{}, error) {
+	return s.getObjects(fmt.Sprintf("%s/locations/%s/templates?type=sms", apiURL, s.config.LocationId), "templates")
+}
+
+// GetTriggerLinks returns the trigger link definitions (id, name, redirectTo) of the location
+func (s *Stoplight) GetTriggerLinks() ([]map[string]interface{}, error) {
+	return s.getObjects(apiURL+"/links/?locationId="+s.config.LocationId, "links")
+}
+
+// GetMediaFiles returns the media library entries (name, type, size, url, parentId folder) of the location
+func (s *Stoplight) GetMediaFiles() ([]map[string]interface{}, error) {
+	return s.getObjects(apiURL+"/medias/files?altType=location&altId="+s.config.LocationId, "files")
+}
+
+// GetPhoneNumbers returns the provisioned phone numbers (number, capabilities, assigned user) of the location
+func (s *Stoplight) GetPhoneNumbers() ([]map[string]interface{}, error) {
+	return s.getObjects(fmt.Sprintf("%s/phone-system/numbers/location/%s", apiURL, s.config.LocationId), "numbers")
+}
+
+// GetUrlRedirects returns the URL redirects (source path, target, hit counts where exposed) of the location
+func (s *Stoplight) GetUrlRedirects() ([]map[string]interface{}, error) {
+	return s.getAllOffsets(apiURL+"/funnels/lookup/redirect/list?locationId="+s.config.LocationId, "data.redirects")
+}
+
+// getObjects requests the API endpoint and returns the objects found under key in the JSON response
+func (s *Stoplight) getObjects(url string, key string) ([]map[string]interface{}, error) {
+	body, err := s.get(url)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Parse the JSON response.
-	var contacts []map[string]interface{}
-	err = json.Unmarshal(body, &contacts)
+	objects, err := parseObjects(body, key)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	s.keepRawPayloads(objects)
 
-	// Return the contacts.
-	return nil
+	return objects, nil
 }
 
-func (s *Stoplight) GetOpportunities() error {
-	// Get the opportunities from the API endpoint.
-	req, err := http.NewRequest("GET", "https://services.leadconnectorhq.com/opportunities/", nil)
+// parseObjects returns the objects found under key in the JSON response. Nested keys are separated by dots
+// (e.g. results.accounts)
+func parseObjects(body []byte, key string) ([]map[string]interface{}, error) {
+	for _, k := range strings.Split(key, ".") {
+		var response map[string]json.RawMessage
+		err := json.Unmarshal(body, &response)
+		if err != nil {
+			return nil, err
+		}
+
+		raw, ok := response[k]
+		if !ok {
+			return nil, nil
+		}
+		body = raw
+	}
+
+	var objects []map[string]interface{}
+	err := json.Unmarshal(body, &objects)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	req.Header.Add("Authorization", s.config.AccessToken)
-	req.Header.Add("Version", s.config.ApiVersion)
+	return objects, nil
+}
 
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return err
+// getAllPages requests every page of a paginated API endpoint and returns the objects found under key
+func (s *Stoplight) getAllPages(url string, key string) ([]map[string]interface{}, error) {
+	var objects []map[string]interface{}
+	for page := 1; ; page++ {
+		pageObjects, err := s.getObjects(fmt.Sprintf("%s%spage=%d&limit=%d", url, querySeparator(url), page, pageSize), key)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, pageObjects...)
+
+		if len(pageObjects) < pageSize {
+			return objects, nil
+		}
 	}
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("Stoplight returned status code %d", resp.StatusCode)
+// getAllOffsets requests every page of an API endpoint paginated with offset and limit parameters
+// and returns the objects found under key
+func (s *Stoplight) getAllOffsets(url string, key string) ([]map[string]interface{}, error) {
+	var objects []map[string]interface{}
+	for offset := 0; ; offset += pageSize {
+		pageObjects, err := s.getObjects(fmt.Sprintf("%s%soffset=%d&limit=%d", url, querySeparator(url), offset, pageSize), key)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, pageObjects...)
+
+		if len(pageObjects) < pageSize {
+			return objects, nil
+		}
 	}
+}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return err
+// querySeparator returns the separator to use for appending query parameters to the url
+func querySeparator(url string) string {
+	if strings.Contains(url, "?") {
+		return "&"
 	}
+	return "?"
+}
 
-	// Parse the JSON response.
-	var opportunities []map[string]interface{}
-	err = json.Unmarshal(body, &opportunities)
+// get requests the API endpoint and returns the response body
+func (s *Stoplight) get(url string) ([]byte, error) {
+	return s.request("GET", url, nil)
+}
+
+// post sends the JSON encoded payload to the API endpoint and returns the response body
+func (s *Stoplight) post(url string, payload interface{}) ([]byte, error) {
+	b, err := json.Marshal(payload)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Return the opportunities.
-	return nil
+	return s.request("POST", url, b)
 }
 
+// request sends the request to the API endpoint and returns the response body. Rate limited requests (429)
+// are retried after the delay advertised by HighLevel in Retry-After header
+func (s *Stoplight) request(method string, url string, payload []byte) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(s.ctx, method, url, bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Add("Authorization", s.config.AccessToken)
+		req.Header.Add("Version", s.config.ApiVersion)
+		if payload != nil {
+			req.Header.Add("Content-Type", "application/json")
+		}
+
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < maxRateLimitRetries {
+			select {
+			case <-s.ctx.Done():
+				return nil, s.ctx.Err()
+			case <-time.After(retryAfter(resp)):
+			}
+			continue
+		}
+
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+			return nil, &apiError{StatusCode: resp.StatusCode}
+		}
+
+		return body, nil
+	}
+}
+
+// apiError is returned when HighLevel responds with a non 200 status code
+type apiError struct {
+	StatusCode int
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("Stoplight returned status code %d", e.StatusCode)
+}
+
+// isNotFound returns true if err is a 404 response, used for endpoints which aren't available for every account
+func isNotFound(err error) bool {
+	var apiErr *apiError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// isUnsupported returns true if err is a response of an endpoint which isn't available for the account or token
+// (not found, forbidden, method not allowed, gone or not implemented)
+func isUnsupported(err error) bool {
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.StatusCode {
+	case http.StatusNotFound, http.StatusForbidden, http.StatusMethodNotAllowed, http.StatusGone, http.StatusNotImplemented:
+		return true
+	}
+	return false
+}
+
+// retryAfter returns the delay before retrying a rate limited request
+func retryAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds <= 0 {
+		return defaultRetryAfter
+	}
+
+	return time.Duration(seconds) * time.Second
+}
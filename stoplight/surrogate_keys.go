@@ -0,0 +1,60 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// surrogateKeyField is the column of the surrogate key of child collections without natural key.
+// The key is the hex SHA-256 digest of the key fields values joined with the unit separator (\x1f)
+const surrogateKeyField = "_key"
+
+// surrogateKeyFields are the fields of the surrogate key of the child collections without natural key
+var surrogateKeyFields = map[string][]string{
+	ContactCustomFieldsCollection: {"contact_id", "field_id"},
+	ContactTagsCollection:         {"contact_id", "tag"},
+	ContactAttributionsCollection: {"contact_id", "attribution_type"},
+	ContactDndSettingsCollection:  {"contact_id", "channel"},
+	DuplicateContactsCollection:   {"contact_id", "duplicate_contact_id", "matched_on"},
+	FunnelPagesCollection:         {"funnel_id", "step_id", "page_id"},
+	CourseOffersCollection:        {"id", "product_id"},
+}
+
+// SurrogateKeyFields returns the fields hashed into the _key column of the collection type
+// or nil if the collection has a natural key
+func (stc *StoplightConfig) SurrogateKeyFields(collectionType string) []string {
+	if explosion := stc.arrayExplosion(collectionType); explosion != nil {
+		foreignKey := explosion.ForeignKey
+		if foreignKey == "" {
+			foreignKey = defaultExplosionForeignKey
+		}
+		return []string{foreignKey, "index"}
+	}
+	return surrogateKeyFields[collectionType]
+}
+
+// surrogateKey returns the stable hash of the fields values of the object
+func surrogateKey(object map[string]interface{}, fields []string) string {
+	values := make([]string, len(fields))
+	for i, field := range fields {
+		values[i] = fmt.Sprint(object[field])
+	}
+	sum := sha256.Sum256([]byte(strings.Join(values, "\x1f")))
+	return hex.EncodeToString(sum[:])
+}
+
+// addSurrogateKeys adds the _key column to the objects of child collections without natural key
+func (s *Stoplight) addSurrogateKeys(objects []map[string]interface{}) {
+	fields := s.config.SurrogateKeyFields(s.collection.Type)
+	if len(fields) == 0 {
+		return
+	}
+
+	for _, object := range objects {
+		object[surrogateKeyField] = surrogateKey(object, fields)
+	}
+}
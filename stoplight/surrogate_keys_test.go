@@ -0,0 +1,45 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+func TestSurrogateKeys(t *testing.T) {
+	s := &Stoplight{
+		config:     &StoplightConfig{},
+		collection: &base.Collection{SourceID: "source", Type: ContactTagsCollection},
+	}
+	objects := []map[string]interface{}{
+		{"contact_id": "c1", "tag": "vip"},
+		{"contact_id": "c1", "tag": "lead"},
+		{"tag": "vip", "contact_id": "c1"},
+	}
+
+	s.addSurrogateKeys(objects)
+	if objects[0][surrogateKeyField] != objects[2][surrogateKeyField] {
+		t.Error("rows with the same key fields have different surrogate keys")
+	}
+	if objects[0][surrogateKeyField] == objects[1][surrogateKeyField] {
+		t.Error("rows with different key fields have the same surrogate key")
+	}
+	if key := surrogateKey(map[string]interface{}{"contact_id": "c1", "tag": "vip"}, []string{"contact_id", "tag"}); key != objects[0][surrogateKeyField] {
+		t.Errorf("surrogate key %s isn't stable", key)
+	}
+}
+
+func TestSurrogateKeyFieldsOfArrayExplosions(t *testing.T) {
+	config := &StoplightConfig{ArrayExplosions: []*ArrayExplosion{
+		{Collection: "opportunity_followers", Parent: OpportunitiesCollection, Field: "followers", ForeignKey: "opportunity_id"},
+	}}
+
+	if fields := config.SurrogateKeyFields("opportunity_followers"); len(fields) != 2 || fields[0] != "opportunity_id" || fields[1] != "index" {
+		t.Errorf("surrogate key fields %v, expected opportunity_id and index", fields)
+	}
+	if fields := config.SurrogateKeyFields(ContactsCollection); fields != nil {
+		t.Errorf("contacts surrogate key fields %v, expected the natural key", fields)
+	}
+}
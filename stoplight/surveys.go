@@ -0,0 +1,29 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"fmt"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+// GetSurveySubmissions returns the survey submissions of the location submitted in the interval
+// with answers flattened the same way as form submissions
+func (s *Stoplight) GetSurveySubmissions(interval *base.TimeInterval) ([]map[string]interface{}, error) {
+	url := fmt.Sprintf("%s/surveys/submissions?locationId=%s&startAt=%s&endAt=%s", apiURL, s.config.LocationId,
+		interval.LowerEndpoint().Format(dateLayout), interval.UpperEndpoint().Format(dateLayout))
+	submissions, err := s.getAllPages(url, "submissions")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, submission := range submissions {
+		err = flattenAnswers(submission, "others")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return submissions, nil
+}
@@ -0,0 +1,73 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SyncStats are the statistics of a sync of a collection: loaded records, tombstones, records quarantined
+// because they don't match the collection schema or typed struct and, when quality rules are configured,
+// the data quality summary
+type SyncStats struct {
+	SyncRunId   string         `json:"sync_run_id"`
+	SyncedAt    time.Time      `json:"synced_at"`
+	Loaded      int            `json:"loaded"`
+	Tombstones  int            `json:"tombstones"`
+	Quarantined int            `json:"quarantined"`
+	Quality     *QualityReport `json:"quality,omitempty"`
+}
+
+// lastSyncStats holds the statistics of the last sync of a driver, read by the application while it syncs
+type lastSyncStats struct {
+	mutex sync.Mutex
+	stats *SyncStats
+}
+
+// count adds the loaded objects to the stats
+func (ss *SyncStats) count(objects []map[string]interface{}) {
+	ss.Loaded += len(objects)
+	for _, object := range objects {
+		if object[deletedField] == true {
+			ss.Tombstones++
+		}
+	}
+}
+
+func (ss *SyncStats) String() string {
+	stats := fmt.Sprintf("loaded: %d, tombstones: %d, quarantined: %d", ss.Loaded, ss.Tombstones, ss.Quarantined)
+	if ss.Quality != nil {
+		stats += ", data quality: " + ss.Quality.String()
+	}
+	return stats
+}
+
+// quarantine counts a record quarantined by the current extraction of the driver
+func (s *Stoplight) quarantine() {
+	atomic.AddInt64(&s.quarantined, 1)
+}
+
+// SyncStats returns the statistics of the last sync of the driver or nil if it didn't sync yet
+func (s *Stoplight) SyncStats() *SyncStats {
+	if s.lastSyncStats == nil {
+		return nil
+	}
+	s.lastSyncStats.mutex.Lock()
+	defer s.lastSyncStats.mutex.Unlock()
+
+	return s.lastSyncStats.stats
+}
+
+// setSyncStats records the statistics of the last sync of the driver
+func (s *Stoplight) setSyncStats(stats *SyncStats) {
+	if s.lastSyncStats == nil {
+		s.lastSyncStats = &lastSyncStats{}
+	}
+	s.lastSyncStats.mutex.Lock()
+	defer s.lastSyncStats.mutex.Unlock()
+
+	s.lastSyncStats.stats = stats
+}
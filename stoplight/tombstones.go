@@ -0,0 +1,49 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"time"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+const (
+	deletedField   = "_deleted"
+	deletedAtField = "_deleted_at"
+)
+
+// tombstone returns the record emitted for a deleted object: its id with _deleted flag, _deleted_at time and delete _change_type,
+// so destinations can purge the row (right to erasure)
+func tombstone(id interface{}, deletedAt time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"id":            id,
+		deletedField:    true,
+		deletedAtField:  deletedAt.UTC().Format(time.RFC3339),
+		changeTypeField: ChangeDelete,
+	}
+}
+
+// LoadDeletions loads tombstone records for the deleted objects ids of the driver collection. It is used
+// by deletion detection (webhooks, full-scan diff) to propagate deletions to the destination. In multi-location
+// mode, the tombstones get the _location_id of the driver location
+func (s *Stoplight) LoadDeletions(ids []string, objectsLoader base.ObjectsLoader) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	objects := make([]map[string]interface{}, 0, len(ids))
+	for _, id := range ids {
+		objects = append(objects, tombstone(id, now))
+	}
+
+	objects, err := s.process(objects)
+	if err != nil {
+		return err
+	}
+	s.addLocationIds(objects)
+	addSyncMetadata(objects, now, newSyncRunId())
+
+	return objectsLoader.Load(objects, 0, len(objects), 100)
+}
@@ -0,0 +1,44 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+// objectsLoaderFunc is an ObjectsLoader calling the function with the loaded objects
+type objectsLoaderFunc func(objects []map[string]interface{}) error
+
+func (f objectsLoaderFunc) Load(objects []map[string]interface{}, pos int, total int, percent int) error {
+	return f(objects)
+}
+
+func TestLoadDeletions(t *testing.T) {
+	s := &Stoplight{
+		config:     &StoplightConfig{LocationId: "location1", LocationIds: []string{"location1", "location2"}},
+		collection: &base.Collection{SourceID: "source", Type: ContactsCollection},
+	}
+
+	var loaded []map[string]interface{}
+	err := s.LoadDeletions([]string{"c1", "c2"}, objectsLoaderFunc(func(objects []map[string]interface{}) error {
+		loaded = append(loaded, objects...)
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(loaded) != 2 {
+		t.Fatalf("loaded %v, expected 2 tombstones", loaded)
+	}
+	for _, object := range loaded {
+		if object[deletedField] != true || object[deletedAtField] == nil || object[changeTypeField] != ChangeDelete {
+			t.Errorf("tombstone %v, expected _deleted, _deleted_at and the delete change type", object)
+		}
+		if object[locationIdField] != "location1" || object[syncRunIdField] == nil {
+			t.Errorf("tombstone %v, expected the location id and the sync metadata", object)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import "sync"
+
+// Transformer enriches, renames or drops fields of a record of the collection before it is loaded.
+// Returning a nil record drops it
+type Transformer func(collection string, record map[string]interface{}) (map[string]interface{}, error)
+
+var (
+	transformersMutex sync.RWMutex
+	transformers      []Transformer
+)
+
+// RegisterTransformer registers a transformer applied by every Stoplight driver instance
+func RegisterTransformer(transformer Transformer) {
+	transformersMutex.Lock()
+	defer transformersMutex.Unlock()
+
+	transformers = append(transformers, transformer)
+}
+
+// AddTransformer adds a transformer applied by this driver instance after the registered ones
+func (s *Stoplight) AddTransformer(transformer Transformer) {
+	s.transformers = append(s.transformers, transformer)
+}
+
+// transform applies the registered and the driver transformers, in order, to the objects
+func (s *Stoplight) transform(objects []map[string]interface{}) ([]map[string]interface{}, error) {
+	transformersMutex.RLock()
+	all := append(append([]Transformer{}, transformers...), s.transformers...)
+	transformersMutex.RUnlock()
+
+	if len(all) == 0 {
+		return objects, nil
+	}
+
+	transformed := objects[:0]
+	for _, object := range objects {
+		var err error
+		for _, transformer := range all {
+			object, err = transformer(s.collection.Type, object)
+			if err != nil {
+				return nil, err
+			}
+			if object == nil {
+				break
+			}
+		}
+		if object != nil {
+			transformed = append(transformed, object)
+		}
+	}
+
+	return transformed, nil
+}
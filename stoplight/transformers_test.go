@@ -0,0 +1,51 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+func TestTransform(t *testing.T) {
+	RegisterTransformer(func(collection string, record map[string]interface{}) (map[string]interface{}, error) {
+		record["order"] = []string{collection}
+		return record, nil
+	})
+	defer func() {
+		transformersMutex.Lock()
+		transformers = nil
+		transformersMutex.Unlock()
+	}()
+
+	s := &Stoplight{
+		config:     &StoplightConfig{},
+		collection: &base.Collection{SourceID: "source", Type: ContactsCollection},
+	}
+	s.AddTransformer(func(collection string, record map[string]interface{}) (map[string]interface{}, error) {
+		if record["id"] == "drop" {
+			return nil, nil
+		}
+		record["order"] = append(record["order"].([]string), "driver")
+		return record, nil
+	})
+
+	objects, err := s.transform([]map[string]interface{}{{"id": "keep"}, {"id": "drop"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []map[string]interface{}{{"id": "keep", "order": []string{ContactsCollection, "driver"}}}
+	if !reflect.DeepEqual(objects, expected) {
+		t.Errorf("transform() = %v, expected %v", objects, expected)
+	}
+
+	s.AddTransformer(func(collection string, record map[string]interface{}) (map[string]interface{}, error) {
+		return nil, errors.New("invalid record")
+	})
+	if _, err := s.transform([]map[string]interface{}{{"id": "keep"}}); err == nil {
+		t.Error("expected the transformer error")
+	}
+}
@@ -0,0 +1,38 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"fmt"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+// GetTriggerLinkClicks returns the clicks (link id, contact id, timestamp) on every trigger link of the location
+// in the interval. Click data isn't exposed for every account: links without a clicks endpoint are skipped
+func (s *Stoplight) GetTriggerLinkClicks(interval *base.TimeInterval) ([]map[string]interface{}, error) {
+	links, err := s.GetTriggerLinks()
+	if err != nil {
+		return nil, err
+	}
+
+	var clicks []map[string]interface{}
+	for _, link := range links {
+		url := fmt.Sprintf("%s/links/%v/clicks?locationId=%s&startAt=%s&endAt=%s", apiURL, link["id"], s.config.LocationId,
+			interval.LowerEndpoint().Format(dateLayout), interval.UpperEndpoint().Format(dateLayout))
+		linkClicks, err := s.getAllPages(url, "clicks")
+		if err != nil {
+			if isNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		for _, click := range linkClicks {
+			click["link_id"] = link["id"]
+		}
+		clicks = append(clicks, linkClicks...)
+	}
+
+	return clicks, nil
+}
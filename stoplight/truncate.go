@@ -0,0 +1,26 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+// truncatedSuffix is the suffix of the boolean column flagging the fields truncated to their max length
+const truncatedSuffix = "_truncated"
+
+// truncate cuts the string fields of the object longer than the max length (in characters) configured for the
+// collection type and sets the <field>_truncated marker column of these fields
+func (s *Stoplight) truncate(object map[string]interface{}) {
+	for field, length := range s.config.MaxLengths[s.collection.Type] {
+		value, ok := object[field]
+		if !ok || value == nil {
+			continue
+		}
+
+		truncated := false
+		if str, ok := value.(string); ok {
+			if runes := []rune(str); len(runes) > length {
+				object[field] = string(runes[:length])
+				truncated = true
+			}
+		}
+		object[field+truncatedSuffix] = truncated
+	}
+}
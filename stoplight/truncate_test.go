@@ -0,0 +1,30 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+func TestTruncate(t *testing.T) {
+	s := &Stoplight{
+		config:     &StoplightConfig{MaxLengths: map[string]map[string]int{ContactsCollection: {"notes": 5, "name": 10, "source": 3}}},
+		collection: &base.Collection{SourceID: "source", Type: ContactsCollection},
+	}
+	object := map[string]interface{}{"id": "c1", "notes": "héllo world", "name": "John"}
+
+	s.truncate(object)
+	expected := map[string]interface{}{
+		"id":              "c1",
+		"notes":           "héllo",
+		"notes_truncated": true,
+		"name":            "John",
+		"name_truncated":  false,
+	}
+	if !reflect.DeepEqual(object, expected) {
+		t.Errorf("truncated %v, expected %v", object, expected)
+	}
+}
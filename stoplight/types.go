@@ -0,0 +1,527 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/jitsucom/jitsu/server/logging"
+)
+
+// Contact is a HighLevel contact
+type Contact struct {
+	Id                    string                `json:"id"`
+	LocationId            string                `json:"locationId,omitempty"`
+	FirstName             string                `json:"firstName,omitempty"`
+	LastName              string                `json:"lastName,omitempty"`
+	ContactName           string                `json:"contactName,omitempty"`
+	CompanyName           string                `json:"companyName,omitempty"`
+	Email                 string                `json:"email,omitempty"`
+	Phone                 string                `json:"phone,omitempty"`
+	Address1              string                `json:"address1,omitempty"`
+	City                  string                `json:"city,omitempty"`
+	State                 string                `json:"state,omitempty"`
+	PostalCode            string                `json:"postalCode,omitempty"`
+	Country               string                `json:"country,omitempty"`
+	Timezone              string                `json:"timezone,omitempty"`
+	Source                string                `json:"source,omitempty"`
+	Type                  string                `json:"type,omitempty"`
+	AssignedTo            string                `json:"assignedTo,omitempty"`
+	Dnd                   bool                  `json:"dnd,omitempty"`
+	DndSettings           map[string]DndSetting `json:"dndSettings,omitempty"`
+	Tags                  []string              `json:"tags,omitempty"`
+	CustomFields          []CustomFieldValue    `json:"customFields,omitempty"`
+	AttributionSource     *AttributionSource    `json:"attributionSource,omitempty"`
+	LastAttributionSource *AttributionSource    `json:"lastAttributionSource,omitempty"`
+	ValidEmail            *bool                 `json:"validEmail,omitempty"`
+	ValidEmailDate        string                `json:"validEmailDate,omitempty"`
+	DateOfBirth           string                `json:"dateOfBirth,omitempty"`
+	DateAdded             string                `json:"dateAdded,omitempty"`
+	DateUpdated           string                `json:"dateUpdated,omitempty"`
+}
+
+// DndSetting is the do not disturb setting of a contact for one channel
+type DndSetting struct {
+	Status  string `json:"status,omitempty"`
+	Message string `json:"message,omitempty"`
+	Code    string `json:"code,omitempty"`
+}
+
+// CustomFieldValue is a value of a custom field of a contact or an opportunity
+type CustomFieldValue struct {
+	Id         string      `json:"id"`
+	Value      interface{} `json:"value,omitempty"`
+	FieldValue interface{} `json:"field_value,omitempty"`
+}
+
+// AttributionSource is the first or last marketing attribution of a contact
+type AttributionSource struct {
+	Url           string `json:"url,omitempty"`
+	Campaign      string `json:"campaign,omitempty"`
+	UtmSource     string `json:"utmSource,omitempty"`
+	UtmMedium     string `json:"utmMedium,omitempty"`
+	UtmContent    string `json:"utmContent,omitempty"`
+	UtmCampaign   string `json:"utmCampaign,omitempty"`
+	UtmTerm       string `json:"utmTerm,omitempty"`
+	Referrer      string `json:"referrer,omitempty"`
+	CampaignId    string `json:"campaignId,omitempty"`
+	Fbclid        string `json:"fbclid,omitempty"`
+	Gclid         string `json:"gclid,omitempty"`
+	Medium        string `json:"medium,omitempty"`
+	MediumId      string `json:"mediumId,omitempty"`
+	SessionSource string `json:"sessionSource,omitempty"`
+	AdName        string `json:"adName,omitempty"`
+	AdGroupId     string `json:"adGroupId,omitempty"`
+	AdId          string `json:"adId,omitempty"`
+}
+
+// Opportunity is a HighLevel pipeline opportunity
+type Opportunity struct {
+	Id              string             `json:"id"`
+	Name            string             `json:"name,omitempty"`
+	LocationId      string             `json:"locationId,omitempty"`
+	PipelineId      string             `json:"pipelineId,omitempty"`
+	PipelineStageId string             `json:"pipelineStageId,omitempty"`
+	Status          string             `json:"status,omitempty"`
+	MonetaryValue   json.Number        `json:"monetaryValue,omitempty"`
+	AssignedTo      string             `json:"assignedTo,omitempty"`
+	Source          string             `json:"source,omitempty"`
+	ContactId       string             `json:"contactId,omitempty"`
+	CustomFields    []CustomFieldValue `json:"customFields,omitempty"`
+	CreatedAt       string             `json:"createdAt,omitempty"`
+	UpdatedAt       string             `json:"updatedAt,omitempty"`
+}
+
+// Pipeline is an opportunities pipeline with its stages
+type Pipeline struct {
+	Id     string          `json:"id"`
+	Name   string          `json:"name,omitempty"`
+	Stages []PipelineStage `json:"stages,omitempty"`
+}
+
+// PipelineStage is a stage of a pipeline
+type PipelineStage struct {
+	Id       string `json:"id"`
+	Name     string `json:"name,omitempty"`
+	Position int    `json:"position,omitempty"`
+}
+
+// Calendar is a HighLevel booking calendar
+type Calendar struct {
+	Id           string `json:"id"`
+	LocationId   string `json:"locationId,omitempty"`
+	GroupId      string `json:"groupId,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Description  string `json:"description,omitempty"`
+	CalendarType string `json:"calendarType,omitempty"`
+	Slug         string `json:"slug,omitempty"`
+	IsActive     bool   `json:"isActive,omitempty"`
+}
+
+// Appointment is a calendar event booked for a contact
+type Appointment struct {
+	Id                string `json:"id"`
+	Title             string `json:"title,omitempty"`
+	CalendarId        string `json:"calendarId,omitempty"`
+	LocationId        string `json:"locationId,omitempty"`
+	ContactId         string `json:"contactId,omitempty"`
+	GroupId           string `json:"groupId,omitempty"`
+	AssignedUserId    string `json:"assignedUserId,omitempty"`
+	AppointmentStatus string `json:"appointmentStatus,omitempty"`
+	StartTime         string `json:"startTime,omitempty"`
+	EndTime           string `json:"endTime,omitempty"`
+	DateAdded         string `json:"dateAdded,omitempty"`
+	DateUpdated       string `json:"dateUpdated,omitempty"`
+}
+
+// Note is a note of a contact or an appointment
+type Note struct {
+	Id        string `json:"id"`
+	Body      string `json:"body,omitempty"`
+	UserId    string `json:"userId,omitempty"`
+	DateAdded string `json:"dateAdded,omitempty"`
+}
+
+// User is a HighLevel user
+type User struct {
+	Id        string `json:"id"`
+	Name      string `json:"name,omitempty"`
+	FirstName string `json:"firstName,omitempty"`
+	LastName  string `json:"lastName,omitempty"`
+	Email     string `json:"email,omitempty"`
+	Phone     string `json:"phone,omitempty"`
+}
+
+// CustomField is a custom field definition
+type CustomField struct {
+	Id              string        `json:"id"`
+	Name            string        `json:"name,omitempty"`
+	FieldKey        string        `json:"fieldKey,omitempty"`
+	DataType        string        `json:"dataType,omitempty"`
+	Model           string        `json:"model,omitempty"`
+	Position        int           `json:"position,omitempty"`
+	PicklistOptions []interface{} `json:"picklistOptions,omitempty"`
+}
+
+// CustomValue is a location custom value
+type CustomValue struct {
+	Id       string `json:"id"`
+	Name     string `json:"name,omitempty"`
+	FieldKey string `json:"fieldKey,omitempty"`
+	Value    string `json:"value,omitempty"`
+}
+
+// Form is a form or a survey definition
+type Form struct {
+	Id         string `json:"id"`
+	Name       string `json:"name,omitempty"`
+	LocationId string `json:"locationId,omitempty"`
+}
+
+// Submission is a form or a survey submission
+type Submission struct {
+	Id        string                 `json:"id"`
+	ContactId string                 `json:"contactId,omitempty"`
+	FormId    string                 `json:"formId,omitempty"`
+	SurveyId  string                 `json:"surveyId,omitempty"`
+	Name      string                 `json:"name,omitempty"`
+	Email     string                 `json:"email,omitempty"`
+	Others    map[string]interface{} `json:"others,omitempty"`
+	CreatedAt string                 `json:"createdAt,omitempty"`
+}
+
+// Workflow is an automation workflow
+type Workflow struct {
+	Id        string `json:"id"`
+	Name      string `json:"name,omitempty"`
+	Status    string `json:"status,omitempty"`
+	Version   int    `json:"version,omitempty"`
+	CreatedAt string `json:"createdAt,omitempty"`
+	UpdatedAt string `json:"updatedAt,omitempty"`
+}
+
+// Campaign is a legacy campaign
+type Campaign struct {
+	Id     string `json:"id"`
+	Name   string `json:"name,omitempty"`
+	Status string `json:"status,omitempty"`
+}
+
+// Template is an email or SMS template
+type Template struct {
+	Id        string `json:"id"`
+	Name      string `json:"name,omitempty"`
+	Type      string `json:"type,omitempty"`
+	DateAdded string `json:"dateAdded,omitempty"`
+	UpdatedAt string `json:"updatedAt,omitempty"`
+}
+
+// TriggerLink is a trigger link definition
+type TriggerLink struct {
+	Id         string `json:"id"`
+	Name       string `json:"name,omitempty"`
+	RedirectTo string `json:"redirectTo,omitempty"`
+	FieldKey   string `json:"fieldKey,omitempty"`
+}
+
+// MediaFile is a media library entry
+type MediaFile struct {
+	Id       string `json:"_id"`
+	Name     string `json:"name,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Size     int64  `json:"size,omitempty"`
+	Url      string `json:"url,omitempty"`
+	ParentId string `json:"parentId,omitempty"`
+}
+
+// Invoice is an issued invoice
+type Invoice struct {
+	Id            string        `json:"_id"`
+	InvoiceNumber string        `json:"invoiceNumber,omitempty"`
+	Name          string        `json:"name,omitempty"`
+	Status        string        `json:"status,omitempty"`
+	Currency      string        `json:"currency,omitempty"`
+	Total         json.Number   `json:"total,omitempty"`
+	AmountPaid    json.Number   `json:"amountPaid,omitempty"`
+	AmountDue     json.Number   `json:"amountDue,omitempty"`
+	IssueDate     string        `json:"issueDate,omitempty"`
+	DueDate       string        `json:"dueDate,omitempty"`
+	InvoiceItems  []InvoiceItem `json:"invoiceItems,omitempty"`
+	CreatedAt     string        `json:"createdAt,omitempty"`
+	UpdatedAt     string        `json:"updatedAt,omitempty"`
+}
+
+// InvoiceItem is a line item of an invoice
+type InvoiceItem struct {
+	Id          string      `json:"_id,omitempty"`
+	Name        string      `json:"name,omitempty"`
+	Description string      `json:"description,omitempty"`
+	ProductId   string      `json:"productId,omitempty"`
+	PriceId     string      `json:"priceId,omitempty"`
+	Currency    string      `json:"currency,omitempty"`
+	Amount      json.Number `json:"amount,omitempty"`
+	Qty         json.Number `json:"qty,omitempty"`
+}
+
+// PaymentOrder is an order of the payments API
+type PaymentOrder struct {
+	Id         string      `json:"_id"`
+	ContactId  string      `json:"contactId,omitempty"`
+	Amount     json.Number `json:"amount,omitempty"`
+	Currency   string      `json:"currency,omitempty"`
+	Status     string      `json:"status,omitempty"`
+	SourceType string      `json:"sourceType,omitempty"`
+	CreatedAt  string      `json:"createdAt,omitempty"`
+}
+
+// PaymentTransaction is a charge of the payments API
+type PaymentTransaction struct {
+	Id              string      `json:"_id"`
+	ContactId       string      `json:"contactId,omitempty"`
+	ChargeId        string      `json:"chargeId,omitempty"`
+	PaymentProvider string      `json:"paymentProviderType,omitempty"`
+	Amount          json.Number `json:"amount,omitempty"`
+	Currency        string      `json:"currency,omitempty"`
+	Status          string      `json:"status,omitempty"`
+	AmountRefunded  json.Number `json:"amountRefunded,omitempty"`
+	CreatedAt       string      `json:"createdAt,omitempty"`
+}
+
+// PaymentSubscription is a recurring subscription of the payments API
+type PaymentSubscription struct {
+	Id              string      `json:"_id"`
+	ContactId       string      `json:"contactId,omitempty"`
+	Status          string      `json:"status,omitempty"`
+	Amount          json.Number `json:"amount,omitempty"`
+	Currency        string      `json:"currency,omitempty"`
+	NextBillingDate string      `json:"nextBillingDate,omitempty"`
+	CreatedAt       string      `json:"createdAt,omitempty"`
+}
+
+// Product is a product of the catalog
+type Product struct {
+	Id          string `json:"_id"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	ProductType string `json:"productType,omitempty"`
+	CreatedAt   string `json:"createdAt,omitempty"`
+	UpdatedAt   string `json:"updatedAt,omitempty"`
+}
+
+// Price is a price or a variant of a product
+type Price struct {
+	Id        string      `json:"_id"`
+	Name      string      `json:"name,omitempty"`
+	Type      string      `json:"type,omitempty"`
+	Amount    json.Number `json:"amount,omitempty"`
+	Currency  string      `json:"currency,omitempty"`
+	Recurring *struct {
+		Interval      string `json:"interval,omitempty"`
+		IntervalCount int    `json:"intervalCount,omitempty"`
+	} `json:"recurring,omitempty"`
+}
+
+// Coupon is a coupon or promo code definition
+type Coupon struct {
+	Id            string      `json:"_id"`
+	Name          string      `json:"name,omitempty"`
+	Code          string      `json:"code,omitempty"`
+	DiscountType  string      `json:"discountType,omitempty"`
+	DiscountValue json.Number `json:"discountValue,omitempty"`
+	Status        string      `json:"status,omitempty"`
+	UsageCount    int         `json:"usageCount,omitempty"`
+}
+
+// Funnel is a funnel or a website definition
+type Funnel struct {
+	Id     string       `json:"_id"`
+	Name   string       `json:"name,omitempty"`
+	Domain string       `json:"domainName,omitempty"`
+	Type   string       `json:"type,omitempty"`
+	Steps  []FunnelStep `json:"steps,omitempty"`
+}
+
+// FunnelStep is a step of a funnel with its pages ids
+type FunnelStep struct {
+	Id    string   `json:"id"`
+	Name  string   `json:"name,omitempty"`
+	Url   string   `json:"url,omitempty"`
+	Pages []string `json:"pages,omitempty"`
+}
+
+// BlogPost is a blog post metadata
+type BlogPost struct {
+	Id          string `json:"_id"`
+	Title       string `json:"title,omitempty"`
+	UrlSlug     string `json:"urlSlug,omitempty"`
+	Author      string `json:"author,omitempty"`
+	Status      string `json:"status,omitempty"`
+	PublishedAt string `json:"publishedAt,omitempty"`
+	UpdatedAt   string `json:"updatedAt,omitempty"`
+}
+
+// SocialAccount is a social account connected to the social planner
+type SocialAccount struct {
+	Id        string `json:"id"`
+	Name      string `json:"name,omitempty"`
+	Platform  string `json:"platform,omitempty"`
+	Type      string `json:"type,omitempty"`
+	IsExpired bool   `json:"isExpired,omitempty"`
+}
+
+// SocialPost is a scheduled or published social planner post
+type SocialPost struct {
+	Id           string   `json:"_id"`
+	Summary      string   `json:"summary,omitempty"`
+	AccountIds   []string `json:"accountIds,omitempty"`
+	Status       string   `json:"status,omitempty"`
+	PublishedAt  string   `json:"publishedAt,omitempty"`
+	ScheduleDate string   `json:"scheduleDate,omitempty"`
+}
+
+// Review is a review collected by the reputation module
+type Review struct {
+	Id        string `json:"id"`
+	ContactId string `json:"contactId,omitempty"`
+	Rating    int    `json:"rating,omitempty"`
+	Source    string `json:"source,omitempty"`
+	Comment   string `json:"comment,omitempty"`
+	Replied   bool   `json:"replied,omitempty"`
+	CreatedAt string `json:"createdAt,omitempty"`
+}
+
+// Association is a relation between two records
+type Association struct {
+	Id           string `json:"id"`
+	FromId       string `json:"from_id"`
+	FromObject   string `json:"from_object,omitempty"`
+	ToId         string `json:"to_id"`
+	ToObject     string `json:"to_object,omitempty"`
+	RelationType string `json:"relation_type,omitempty"`
+}
+
+// collectionTypes are the typed structs of the collections whose records are API response objects
+var collectionTypes = map[string]reflect.Type{
+	CalendarsCollection:            reflect.TypeOf(Calendar{}),
+	ContactsCollection:             reflect.TypeOf(Contact{}),
+	OpportunitiesCollection:        reflect.TypeOf(Opportunity{}),
+	CustomFieldsCollection:         reflect.TypeOf(CustomField{}),
+	CustomValuesCollection:         reflect.TypeOf(CustomValue{}),
+	FormsCollection:                reflect.TypeOf(Form{}),
+	FormSubmissionsCollection:      reflect.TypeOf(Submission{}),
+	SurveysCollection:              reflect.TypeOf(Form{}),
+	SurveySubmissionsCollection:    reflect.TypeOf(Submission{}),
+	WorkflowsCollection:            reflect.TypeOf(Workflow{}),
+	CampaignsCollection:            reflect.TypeOf(Campaign{}),
+	EmailTemplatesCollection:       reflect.TypeOf(Template{}),
+	SmsTemplatesCollection:         reflect.TypeOf(Template{}),
+	TriggerLinksCollection:         reflect.TypeOf(TriggerLink{}),
+	MediaFilesCollection:           reflect.TypeOf(MediaFile{}),
+	InvoicesCollection:             reflect.TypeOf(Invoice{}),
+	InvoiceItemsCollection:         reflect.TypeOf(InvoiceItem{}),
+	PaymentOrdersCollection:        reflect.TypeOf(PaymentOrder{}),
+	PaymentTransactionsCollection:  reflect.TypeOf(PaymentTransaction{}),
+	PaymentSubscriptionsCollection: reflect.TypeOf(PaymentSubscription{}),
+	ProductsCollection:             reflect.TypeOf(Product{}),
+	ProductPricesCollection:        reflect.TypeOf(Price{}),
+	CouponsCollection:              reflect.TypeOf(Coupon{}),
+	FunnelsCollection:              reflect.TypeOf(Funnel{}),
+	BlogPostsCollection:            reflect.TypeOf(BlogPost{}),
+	SocialAccountsCollection:       reflect.TypeOf(SocialAccount{}),
+	SocialPostsCollection:          reflect.TypeOf(SocialPost{}),
+	ReviewsCollection:              reflect.TypeOf(Review{}),
+	AssociationsCollection:         reflect.TypeOf(Association{}),
+}
+
+// DecodeCollection converts the API objects of the collection type into a slice of its typed struct,
+// e.g. []Contact for contacts. It fails if the collection has no typed struct or any object can't be decoded
+func DecodeCollection(collectionType string, objects []map[string]interface{}) (interface{}, error) {
+	structType, ok := collectionTypes[collectionType]
+	if !ok {
+		return nil, fmt.Errorf("Stoplight collection %s has no typed struct", collectionType)
+	}
+
+	target := reflect.New(reflect.SliceOf(structType))
+	err := Decode(objects, target.Interface())
+	if err != nil {
+		return nil, err
+	}
+
+	return target.Elem().Interface(), nil
+}
+
+// Decode converts the objects returned by the driver into a slice of typed structs, e.g.
+//
+//	var contacts []Contact
+//	err := Decode(objects, &contacts)
+//
+// It fails if any object can't be decoded (e.g. a field of an unexpected type)
+func Decode(objects []map[string]interface{}, target interface{}) error {
+	b, err := json.Marshal(objects)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, target)
+}
+
+// decode converts the objects into the slice of typed structs pointed by target one by one: the API may return
+// fields of unexpected types, so the objects which can't be decoded are quarantined with a warning rather than
+// failing the sync
+func (s *Stoplight) decode(objects []map[string]interface{}, target interface{}) error {
+	slice := reflect.ValueOf(target)
+	if slice.Kind() != reflect.Ptr || slice.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("decode target must be a pointer to a slice, got %T", target)
+	}
+	slice = slice.Elem()
+
+	decoded := reflect.MakeSlice(slice.Type(), 0, len(objects))
+	for _, object := range objects {
+		element := reflect.New(slice.Type().Elem())
+		err := decodeObject(object, element.Interface())
+		if err != nil {
+			s.quarantine()
+			logging.Warnf("[%s] Quarantined %s object %v which can't be decoded as %s: %v", s.collection.SourceID,
+				s.collection.Type, objectId(object), slice.Type().Elem().Name(), err)
+			continue
+		}
+		decoded = reflect.Append(decoded, element.Elem())
+	}
+
+	slice.Set(decoded)
+	return nil
+}
+
+// checkTypes quarantines the objects of the collection type which can't be decoded as its typed struct, so the
+// loaded records match the structs exposed to programmatic consumers
+func (s *Stoplight) checkTypes(collectionType string, objects []map[string]interface{}) []map[string]interface{} {
+	structType, ok := collectionTypes[collectionType]
+	if !ok {
+		return objects
+	}
+
+	valid := objects[:0]
+	for _, object := range objects {
+		err := decodeObject(object, reflect.New(structType).Interface())
+		if err != nil {
+			s.quarantine()
+			logging.Warnf("[%s] Quarantined %s object %v which can't be decoded as %s: %v", s.collection.SourceID,
+				collectionType, objectId(object), structType.Name(), err)
+			continue
+		}
+		valid = append(valid, object)
+	}
+
+	return valid
+}
+
+// decodeObject decodes the object into the struct pointed by target
+func decodeObject(object map[string]interface{}, target interface{}) error {
+	b, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, target)
+}
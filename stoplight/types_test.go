@@ -0,0 +1,68 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+func TestDecodeQuarantinesUndecodableObjects(t *testing.T) {
+	s := &Stoplight{collection: &base.Collection{SourceID: "source", Type: ContactsCollection}}
+	objects := []map[string]interface{}{
+		{"id": "c1", "email": "ann@example.com"},
+		{"id": "c2", "tags": "not a list"},
+		{"id": "c3", "dnd": true},
+	}
+
+	var contacts []Contact
+	err := s.decode(objects, &contacts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(contacts) != 2 || contacts[0].Id != "c1" || contacts[1].Id != "c3" || !contacts[1].Dnd {
+		t.Errorf("decoded %+v, expected c1 and c3", contacts)
+	}
+
+	if s.quarantined != 1 {
+		t.Errorf("%d quarantined objects, expected 1", s.quarantined)
+	}
+
+	if Decode(objects, &contacts) == nil {
+		t.Error("Decode succeeded with an undecodable object")
+	}
+}
+
+func TestCheckTypesQuarantinesUndecodableObjects(t *testing.T) {
+	s := &Stoplight{collection: &base.Collection{SourceID: "source", Type: OpportunitiesCollection}}
+	objects := []map[string]interface{}{
+		{"id": "o1", "monetaryValue": 100.0},
+		{"id": "o2", "customFields": "not a list"},
+	}
+
+	objects = s.checkTypes(OpportunitiesCollection, objects)
+	if len(objects) != 1 || objects[0]["id"] != "o1" || s.quarantined != 1 {
+		t.Errorf("kept %v with %d quarantined objects, expected o1 with 1 quarantined object", objects, s.quarantined)
+	}
+
+	objects = []map[string]interface{}{{"id": "o2", "customFields": "not a list"}}
+	if kept := s.checkTypes(FunnelPagesCollection, objects); len(kept) != 1 {
+		t.Errorf("kept %v, expected the objects of collections without typed struct to be kept", kept)
+	}
+}
+
+func TestDecodeCollection(t *testing.T) {
+	decoded, err := DecodeCollection(CalendarsCollection, []map[string]interface{}{{"id": "cal1", "name": "Demo"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	calendars, ok := decoded.([]Calendar)
+	if !ok || len(calendars) != 1 || calendars[0].Name != "Demo" {
+		t.Errorf("decoded %#v, expected the Demo calendar", decoded)
+	}
+
+	if _, err = DecodeCollection(FunnelPagesCollection, nil); err == nil {
+		t.Error("funnel pages decoded without typed struct")
+	}
+}
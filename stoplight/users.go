@@ -0,0 +1,40 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"fmt"
+	"strings"
+)
+
+// enrichUsers adds the <prefix>_name and <prefix>_email columns of the user referenced by the field
+// of the objects from the cached users of the location. Unknown users get null columns
+func (s *Stoplight) enrichUsers(objects []map[string]interface{}, field string, prefix string) error {
+	users, err := s.usersById()
+	if err != nil {
+		return err
+	}
+
+	for _, object := range objects {
+		object[prefix+"_name"] = nil
+		object[prefix+"_email"] = nil
+
+		id, ok := object[field]
+		if !ok || id == nil {
+			continue
+		}
+		user, ok := users[fmt.Sprint(id)]
+		if !ok {
+			continue
+		}
+
+		name := user.Name
+		if name == "" {
+			name = strings.TrimSpace(user.FirstName + " " + user.LastName)
+		}
+		object[prefix+"_name"] = name
+		object[prefix+"_email"] = user.Email
+	}
+
+	return nil
+}
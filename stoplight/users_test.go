@@ -0,0 +1,41 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+func TestEnrichUsers(t *testing.T) {
+	s := &Stoplight{
+		config:     &StoplightConfig{},
+		collection: &base.Collection{SourceID: "source", Type: OpportunitiesCollection},
+		usersCache: map[string]User{
+			"u1": {Id: "u1", FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com"},
+			"u2": {Id: "u2", Name: "Grace", Email: "grace@example.com"},
+		},
+	}
+	objects := []map[string]interface{}{
+		{"id": "o1", "assignedTo": "u1"},
+		{"id": "o2", "assignedTo": "u2"},
+		{"id": "o3", "assignedTo": "gone"},
+		{"id": "o4"},
+	}
+
+	if err := s.enrichUsers(objects, "assignedTo", "assigned_to"); err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct{ name, email interface{} }{
+		{"Ada Lovelace", "ada@example.com"},
+		{"Grace", "grace@example.com"},
+		{nil, nil},
+		{nil, nil},
+	}
+	for i, e := range expected {
+		if objects[i]["assigned_to_name"] != e.name || objects[i]["assigned_to_email"] != e.email {
+			t.Errorf("object %v, expected %v <%v>", objects[i], e.name, e.email)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+const (
+	SchemaValidationFail       = "fail"
+	SchemaValidationQuarantine = "quarantine"
+)
+
+//go:embed schemas/*.json
+var schemasFS embed.FS
+
+var (
+	schemasMutex sync.Mutex
+	schemas      = map[string]*jsonschema.Schema{}
+)
+
+// collectionSchema returns the compiled embedded schema of the collection type or nil if the collection
+// doesn't have one (e.g. custom objects)
+func collectionSchema(collectionType string) (*jsonschema.Schema, error) {
+	schemasMutex.Lock()
+	defer schemasMutex.Unlock()
+
+	if schema, ok := schemas[collectionType]; ok {
+		return schema, nil
+	}
+
+	path := "schemas/" + collectionType + ".json"
+	b, err := schemasFS.ReadFile(path)
+	if err != nil {
+		schemas[collectionType] = nil
+		return nil, nil
+	}
+
+	compiler := jsonschema.NewCompiler()
+	err = compiler.AddResource(path, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	schema, err := compiler.Compile(path)
+	if err != nil {
+		return nil, err
+	}
+	schemas[collectionType] = schema
+
+	return schema, nil
+}
+
+// validate validates the objects against the embedded schema of the collection type. In fail mode the first
+// invalid object fails the sync, in quarantine mode invalid objects are logged and skipped
+func (s *Stoplight) validate(collectionType string, objects []map[string]interface{}) ([]map[string]interface{}, error) {
+	schema, err := collectionSchema(collectionType)
+	if err != nil || schema == nil {
+		return objects, err
+	}
+
+	valid := objects[:0]
+	for _, object := range objects {
+		err = schema.Validate(map[string]interface{}(object))
+		if err == nil {
+			valid = append(valid, object)
+			continue
+		}
+
+		var validationErr *jsonschema.ValidationError
+		if !errors.As(err, &validationErr) || s.config.SchemaValidation == SchemaValidationFail {
+			return nil, fmt.Errorf("Stoplight %s object %v doesn't match the collection schema: %v", collectionType, object["id"], err)
+		}
+		s.quarantine()
+		logging.Warnf("[%s] Quarantined %s object %v not matching the collection schema: %v", s.collection.SourceID, collectionType, object["id"], err)
+	}
+
+	return valid, nil
+}
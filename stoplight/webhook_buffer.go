@@ -0,0 +1,623 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/jitsucom/jitsu/server/logging"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	WebhookBufferDisk  = "disk"
+	WebhookBufferBolt  = "bolt"
+	WebhookBufferRedis = "redis"
+
+	defaultWebhookBufferRedisKey = "stoplight:webhooks"
+	webhookReplayBatchSize       = 100
+)
+
+var webhookBufferBucket = []byte("webhook_events")
+
+// WebhookBuffer persists the received webhook events, in order, until they are loaded
+type WebhookBuffer interface {
+	// Append adds the event at the end of the buffer
+	Append(event *WebhookEvent) error
+	// Pending returns the limit oldest events of the buffer
+	Pending(limit int) ([]*BufferedWebhookEvent, error)
+	// Update replaces the buffered event, keeping its position in the buffer
+	Update(id string, event *WebhookEvent) error
+	// Remove removes the loaded events from the buffer
+	Remove(ids ...string) error
+	Close() error
+}
+
+// BufferedWebhookEvent is a webhook event of a buffer with its buffer id
+type BufferedWebhookEvent struct {
+	Id    string        `json:"id"`
+	Event *WebhookEvent `json:"event"`
+}
+
+// WebhookLoadFunc loads the records of a collection converted from a webhook event
+type WebhookLoadFunc func(collection string, objects []map[string]interface{}) error
+
+// WebhookBufferConfig is the configuration of the webhook events buffer: a directory (disk), a BoltDB file (bolt)
+// or a Redis list (redis)
+type WebhookBufferConfig struct {
+	Type     string `mapstructure:"type" json:"type,omitempty" yaml:"type,omitempty"`
+	Path     string `mapstructure:"path" json:"path,omitempty" yaml:"path,omitempty"`
+	RedisURL string `mapstructure:"redis_url" json:"redis_url,omitempty" yaml:"redis_url,omitempty"`
+	RedisKey string `mapstructure:"redis_key" json:"redis_key,omitempty" yaml:"redis_key,omitempty"`
+}
+
+// Validate returns an error if the webhook buffer configuration is invalid
+func (bc *WebhookBufferConfig) Validate() error {
+	switch bc.Type {
+	case WebhookBufferDisk, WebhookBufferBolt:
+		if bc.Path == "" {
+			return fmt.Errorf("Stoplight webhooks buffer path is required for %s buffer", bc.Type)
+		}
+	case WebhookBufferRedis:
+		if bc.RedisURL == "" {
+			return errors.New("Stoplight webhooks buffer redis_url is required for redis buffer")
+		}
+	default:
+		return fmt.Errorf("Stoplight webhooks buffer type %q is not supported: use disk, bolt or redis", bc.Type)
+	}
+
+	return nil
+}
+
+// Open opens the configured webhook buffer
+func (bc *WebhookBufferConfig) Open() (WebhookBuffer, error) {
+	switch bc.Type {
+	case WebhookBufferDisk:
+		return NewDiskWebhookBuffer(bc.Path)
+	case WebhookBufferBolt:
+		return NewBoltWebhookBuffer(bc.Path)
+	case WebhookBufferRedis:
+		key := bc.RedisKey
+		if key == "" {
+			key = defaultWebhookBufferRedisKey
+		}
+		return NewRedisWebhookBuffer(bc.RedisURL, key), nil
+	default:
+		return nil, fmt.Errorf("webhook buffer type %q is not supported", bc.Type)
+	}
+}
+
+// BufferWebhookEvents returns the webhook handler appending the verified events to the buffer, so they aren't
+// lost while the destination is down
+func BufferWebhookEvents(buffer WebhookBuffer) WebhookHandlerFunc {
+	return buffer.Append
+}
+
+// ReplayWebhookEvents routes the buffered events, in order, and loads their records in batches of batch_size
+// events: the records of a batch are loaded with one load call per collection. Events are removed from the buffer
+// once loaded. When a batch fails, the events of the collections already loaded are removed and the other ones
+// are replayed one by one: without dead letter queue, replay stops at the first error and the remaining events
+// are replayed by the next call. With a dead letter queue, events
+// failing max_attempts times are moved to it so they don't block the buffer. It returns the number of replayed events
+func (s *Stoplight) ReplayWebhookEvents(buffer WebhookBuffer, deadLetters WebhookBuffer, load WebhookLoadFunc) (int, error) {
+	replayed := 0
+	for {
+		events, err := buffer.Pending(s.config.Webhooks.batchSize())
+		if err != nil {
+			return replayed, err
+		}
+		if len(events) == 0 {
+			return replayed, nil
+		}
+
+		done, err := s.replayWebhookBatch(events, load)
+		if len(done) > 0 {
+			removeErr := buffer.Remove(bufferedIds(done)...)
+			if removeErr != nil {
+				return replayed, removeErr
+			}
+			replayed += len(done)
+		}
+		if err != nil {
+			logging.Warnf("[%s] webhook events batch failed, replaying its events one by one: %v", s.collection.SourceID, err)
+			n, err := s.replayWebhookEvents(buffer, deadLetters, remainingEvents(events, done), load)
+			replayed += n
+			if err != nil {
+				return replayed, err
+			}
+		}
+	}
+}
+
+// bufferedIds returns the buffer ids of the events
+func bufferedIds(events []*BufferedWebhookEvent) []string {
+	ids := make([]string, 0, len(events))
+	for _, buffered := range events {
+		ids = append(ids, buffered.Id)
+	}
+	return ids
+}
+
+// remainingEvents returns the events which aren't done, in order
+func remainingEvents(events []*BufferedWebhookEvent, done []*BufferedWebhookEvent) []*BufferedWebhookEvent {
+	doneIds := make(map[string]bool, len(done))
+	for _, buffered := range done {
+		doneIds[buffered.Id] = true
+	}
+
+	var remaining []*BufferedWebhookEvent
+	for _, buffered := range events {
+		if !doneIds[buffered.Id] {
+			remaining = append(remaining, buffered)
+		}
+	}
+	return remaining
+}
+
+// RunWebhookReplay replays the buffered events every batch_window until stop is closed, so the events received
+// during a window are loaded together
+func (s *Stoplight) RunWebhookReplay(buffer WebhookBuffer, deadLetters WebhookBuffer, load WebhookLoadFunc, stop <-chan struct{}) {
+	ticker := time.NewTicker(s.config.Webhooks.batchWindow())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			_, err := s.ReplayWebhookEvents(buffer, deadLetters, load)
+			if err != nil {
+				logging.Warnf("[%s] error replaying webhook events: %v", s.collection.SourceID, err)
+			}
+		}
+	}
+}
+
+// webhookCollectionBatch are the events of a batch routed to a collection with their records and pending versions
+type webhookCollectionBatch struct {
+	events   []*BufferedWebhookEvent
+	objects  []map[string]interface{}
+	pendings []*pendingVersions
+}
+
+// replayWebhookBatch routes the events and loads their records with one load call per collection, then records
+// the loaded versions. It returns the events done (not routed or of the collections loaded), also when the load
+// of a collection fails so they aren't loaded again
+func (s *Stoplight) replayWebhookBatch(events []*BufferedWebhookEvent, load WebhookLoadFunc) ([]*BufferedWebhookEvent, error) {
+	var done []*BufferedWebhookEvent
+	var collections []string
+	batches := map[string]*webhookCollectionBatch{}
+	for _, buffered := range events {
+		driver, objects, pending, err := s.routeWebhookEvent(buffered.Event)
+		if err != nil {
+			return nil, err
+		}
+		if driver == nil {
+			done = append(done, buffered)
+			continue
+		}
+
+		collection := driver.collection.Type
+		batch, ok := batches[collection]
+		if !ok {
+			batch = &webhookCollectionBatch{}
+			batches[collection] = batch
+			collections = append(collections, collection)
+		}
+		batch.events = append(batch.events, buffered)
+		batch.objects = append(batch.objects, objects...)
+		batch.pendings = append(batch.pendings, pending)
+	}
+
+	for _, collection := range collections {
+		batch := batches[collection]
+		if len(batch.objects) > 0 {
+			err := load(collection, batch.objects)
+			if err != nil {
+				return done, err
+			}
+			s.publish(collection, batch.objects)
+		}
+		done = append(done, batch.events...)
+
+		for _, pending := range batch.pendings {
+			err := pending.commit()
+			if err != nil {
+				return done, err
+			}
+		}
+	}
+
+	return done, nil
+}
+
+// replayWebhookEvents replays the events one by one, moving the failing ones to the dead letter queue
+func (s *Stoplight) replayWebhookEvents(buffer WebhookBuffer, deadLetters WebhookBuffer, events []*BufferedWebhookEvent, load WebhookLoadFunc) (int, error) {
+	replayed := 0
+	for _, buffered := range events {
+		err := s.replayWebhookEvent(buffered.Event, load)
+		if err != nil {
+			if deadLetters == nil {
+				return replayed, err
+			}
+			err = s.deadLetter(buffer, buffered, deadLetters, err)
+			if err != nil {
+				return replayed, err
+			}
+		}
+
+		err = buffer.Remove(buffered.Id)
+		if err != nil {
+			return replayed, err
+		}
+		replayed++
+	}
+
+	return replayed, nil
+}
+
+// replayWebhookEvent routes the event and loads its records
+func (s *Stoplight) replayWebhookEvent(event *WebhookEvent, load WebhookLoadFunc) error {
+	driver, objects, pending, err := s.routeWebhookEvent(event)
+	if err != nil || driver == nil {
+		return err
+	}
+
+	if len(objects) > 0 {
+		err = load(driver.collection.Type, objects)
+		if err != nil {
+			return err
+		}
+		s.publish(driver.collection.Type, objects)
+	}
+
+	return pending.commit()
+}
+
+// diskWebhookBuffer stores the events as JSON files of a directory named by their sequence
+type diskWebhookBuffer struct {
+	mutex    sync.Mutex
+	dir      string
+	sequence int64
+}
+
+// NewDiskWebhookBuffer returns a webhook buffer storing the events in the directory. The sequence starts after the
+// highest sequence on disk so the events appended after a restart (or a clock moving backwards) stay in order
+func NewDiskWebhookBuffer(dir string) (WebhookBuffer, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	db := &diskWebhookBuffer{dir: dir}
+	for _, file := range files {
+		sequence, err := strconv.ParseInt(strings.TrimSuffix(file.Name(), ".json"), 10, 64)
+		if err == nil && sequence > db.sequence {
+			db.sequence = sequence
+		}
+	}
+	return db, nil
+}
+
+func (db *diskWebhookBuffer) Append(event *WebhookEvent) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	sequence := time.Now().UnixNano()
+	if sequence <= db.sequence {
+		sequence = db.sequence + 1
+	}
+	db.sequence = sequence
+
+	return db.write(fmt.Sprintf("%020d.json", sequence), b)
+}
+
+// write writes the event file, to a temporary file first so a crash never leaves a truncated event
+func (db *diskWebhookBuffer) write(name string, b []byte) error {
+	path := filepath.Join(db.dir, name)
+	err := ioutil.WriteFile(path+".tmp", b, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func (db *diskWebhookBuffer) Pending(limit int) ([]*BufferedWebhookEvent, error) {
+	files, err := ioutil.ReadDir(db.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []*BufferedWebhookEvent
+	for _, file := range files {
+		if len(events) == limit {
+			break
+		}
+		if !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+
+		b, err := ioutil.ReadFile(filepath.Join(db.dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		event := &WebhookEvent{}
+		err = json.Unmarshal(b, event)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading buffered webhook event %s: %v", file.Name(), err)
+		}
+		events = append(events, &BufferedWebhookEvent{Id: file.Name(), Event: event})
+	}
+
+	return events, nil
+}
+
+func (db *diskWebhookBuffer) Update(id string, event *WebhookEvent) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return db.write(filepath.Base(id), b)
+}
+
+func (db *diskWebhookBuffer) Remove(ids ...string) error {
+	for _, id := range ids {
+		err := os.Remove(filepath.Join(db.dir, filepath.Base(id)))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *diskWebhookBuffer) Close() error {
+	return nil
+}
+
+// boltWebhookBuffer stores the events in a BoltDB bucket keyed by their big endian sequence
+type boltWebhookBuffer struct {
+	db *bolt.DB
+}
+
+// NewBoltWebhookBuffer returns a webhook buffer storing the events in the BoltDB file
+func NewBoltWebhookBuffer(path string) (WebhookBuffer, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(webhookBufferBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &boltWebhookBuffer{db: db}, nil
+}
+
+func (bb *boltWebhookBuffer) Append(event *WebhookEvent) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return bb.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(webhookBufferBucket)
+		sequence, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, sequence)
+		return bucket.Put(key, b)
+	})
+}
+
+func (bb *boltWebhookBuffer) Pending(limit int) ([]*BufferedWebhookEvent, error) {
+	var events []*BufferedWebhookEvent
+	err := bb.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(webhookBufferBucket).Cursor()
+		for k, v := cursor.First(); k != nil && len(events) < limit; k, v = cursor.Next() {
+			event := &WebhookEvent{}
+			err := json.Unmarshal(v, event)
+			if err != nil {
+				return err
+			}
+			id := strconv.FormatUint(binary.BigEndian.Uint64(k), 10)
+			events = append(events, &BufferedWebhookEvent{Id: id, Event: event})
+		}
+		return nil
+	})
+
+	return events, err
+}
+
+func (bb *boltWebhookBuffer) Update(id string, event *WebhookEvent) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	sequence, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	return bb.db.Update(func(tx *bolt.Tx) error {
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, sequence)
+		return tx.Bucket(webhookBufferBucket).Put(key, b)
+	})
+}
+
+func (bb *boltWebhookBuffer) Remove(ids ...string) error {
+	return bb.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(webhookBufferBucket)
+		for _, id := range ids {
+			sequence, err := strconv.ParseUint(id, 10, 64)
+			if err != nil {
+				return err
+			}
+			key := make([]byte, 8)
+			binary.BigEndian.PutUint64(key, sequence)
+			err = bucket.Delete(key)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (bb *boltWebhookBuffer) Close() error {
+	return bb.db.Close()
+}
+
+// redisWebhookBuffer stores the events in the <key>:events hash and their ids, in order, in the <key>:queue list
+type redisWebhookBuffer struct {
+	pool *redis.Pool
+	key  string
+}
+
+// NewRedisWebhookBuffer returns a webhook buffer storing the events in Redis under the key
+func NewRedisWebhookBuffer(url string, key string) WebhookBuffer {
+	return &redisWebhookBuffer{
+		pool: &redis.Pool{
+			Dial:        func() (redis.Conn, error) { return redis.DialURL(url) },
+			MaxIdle:     2,
+			IdleTimeout: 5 * time.Minute,
+		},
+		key: key,
+	}
+}
+
+func (rb *redisWebhookBuffer) Append(event *WebhookEvent) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	conn := rb.pool.Get()
+	defer conn.Close()
+
+	sequence, err := redis.Int64(conn.Do("INCR", rb.key+":sequence"))
+	if err != nil {
+		return err
+	}
+	id := strconv.FormatInt(sequence, 10)
+
+	// the event and its queued id are written in one transaction so a crash can't lose the event: a sequence
+	// number lost with a failed transaction only leaves a gap
+	err = conn.Send("MULTI")
+	if err != nil {
+		return err
+	}
+	err = conn.Send("HSET", rb.key+":events", id, b)
+	if err != nil {
+		return err
+	}
+	err = conn.Send("RPUSH", rb.key+":queue", id)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Do("EXEC")
+	return err
+}
+
+// Pending removes the queued ids without event (e.g. a crash between HDEL and LREM) so they don't block the queue
+func (rb *redisWebhookBuffer) Pending(limit int) ([]*BufferedWebhookEvent, error) {
+	conn := rb.pool.Get()
+	defer conn.Close()
+
+	for {
+		ids, err := redis.Strings(conn.Do("LRANGE", rb.key+":queue", 0, limit-1))
+		if err != nil || len(ids) == 0 {
+			return nil, err
+		}
+		values, err := redis.ByteSlices(conn.Do("HMGET", redis.Args{}.Add(rb.key+":events").AddFlat(ids)...))
+		if err != nil {
+			return nil, err
+		}
+
+		events := make([]*BufferedWebhookEvent, 0, len(ids))
+		missing := 0
+		for i, id := range ids {
+			if values[i] == nil {
+				logging.Warnf("Removing buffered webhook event %s without payload from %s", id, rb.key)
+				_, err = conn.Do("LREM", rb.key+":queue", 1, id)
+				if err != nil {
+					return nil, err
+				}
+				missing++
+				continue
+			}
+			event := &WebhookEvent{}
+			err = json.Unmarshal(values[i], event)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, &BufferedWebhookEvent{Id: id, Event: event})
+		}
+
+		// a page of missing events only: read the next one
+		if len(events) > 0 || missing == 0 {
+			return events, nil
+		}
+	}
+}
+
+func (rb *redisWebhookBuffer) Update(id string, event *WebhookEvent) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	conn := rb.pool.Get()
+	defer conn.Close()
+
+	_, err = conn.Do("HSET", rb.key+":events", id, b)
+	return err
+}
+
+func (rb *redisWebhookBuffer) Remove(ids ...string) error {
+	conn := rb.pool.Get()
+	defer conn.Close()
+
+	for _, id := range ids {
+		_, err := conn.Do("LREM", rb.key+":queue", 1, id)
+		if err != nil {
+			return err
+		}
+		_, err = conn.Do("HDEL", rb.key+":events", id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (rb *redisWebhookBuffer) Close() error {
+	return rb.pool.Close()
+}
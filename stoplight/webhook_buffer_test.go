@@ -0,0 +1,140 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+func TestWebhookAttemptsSurviveRestarts(t *testing.T) {
+	dir := t.TempDir()
+	failing := func(collection string, objects []map[string]interface{}) error {
+		return errors.New("destination is down")
+	}
+	// each replay runs with a new driver and new buffers, as after a restart
+	replay := func() (WebhookBuffer, WebhookBuffer) {
+		buffer, err := NewDiskWebhookBuffer(filepath.Join(dir, "buffer"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		deadLetters, err := NewDiskWebhookBuffer(filepath.Join(dir, "dead_letters"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := &Stoplight{
+			config:     &StoplightConfig{Currency: "USD", Webhooks: &WebhookConfig{MaxAttempts: 2}},
+			collection: &base.Collection{SourceID: "source"},
+		}
+		_, _ = s.ReplayWebhookEvents(buffer, deadLetters, failing)
+		return buffer, deadLetters
+	}
+
+	buffer, err := NewDiskWebhookBuffer(filepath.Join(dir, "buffer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = buffer.Append(&WebhookEvent{Type: "OpportunityUpdate", LocationId: "location1", Payload: map[string]interface{}{"id": "o1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buffer, _ = replay()
+	pending, err := buffer.Pending(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].Event.Attempts != 1 {
+		t.Fatalf("buffer after the first failure: %v, expected the event with 1 attempt", pending)
+	}
+
+	buffer, deadLetters := replay()
+	pending, err = buffer.Pending(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("buffer after the second failure: %v, expected it empty", pending)
+	}
+	letters, err := deadLetters.Pending(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(letters) != 1 || letters[0].Event.Attempts != 2 || letters[0].Event.Error != "destination is down" {
+		t.Errorf("dead letters: %v, expected the event after 2 attempts", letters)
+	}
+}
+
+func TestDiskWebhookBufferSequenceAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	// an event appended before a restart with a clock ahead of the current one
+	err := ioutil.WriteFile(filepath.Join(dir, "09000000000000000000.json"), []byte(`{"type":"ContactCreate"}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buffer, err := NewDiskWebhookBuffer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = buffer.Append(&WebhookEvent{Type: "ContactUpdate"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err := buffer.Pending(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 2 || pending[0].Event.Type != "ContactCreate" || pending[1].Event.Type != "ContactUpdate" {
+		t.Errorf("pending events %v, expected ContactCreate then ContactUpdate", pending)
+	}
+}
+
+func TestWebhookBatchDoesntReloadLoadedCollections(t *testing.T) {
+	buffer, err := NewDiskWebhookBuffer(filepath.Join(t.TempDir(), "buffer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, event := range []*WebhookEvent{
+		{Type: "ProductUpdate", LocationId: "location1", Payload: map[string]interface{}{"id": "p1"}},
+		{Type: "OpportunityUpdate", LocationId: "location1", Payload: map[string]interface{}{"id": "o1"}},
+	} {
+		err = buffer.Append(event)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	loads := map[string]int{}
+	load := func(collection string, objects []map[string]interface{}) error {
+		loads[collection]++
+		if collection == OpportunitiesCollection {
+			return errors.New("destination is down")
+		}
+		return nil
+	}
+	s := &Stoplight{
+		config:     &StoplightConfig{Currency: "USD", Webhooks: &WebhookConfig{MaxAttempts: 2}},
+		collection: &base.Collection{SourceID: "source"},
+	}
+	replayed, _ := s.ReplayWebhookEvents(buffer, nil, load)
+	if replayed != 1 {
+		t.Errorf("replayed %d events, expected the product event", replayed)
+	}
+	if loads[ProductsCollection] != 1 {
+		t.Errorf("products loaded %d times, expected once", loads[ProductsCollection])
+	}
+
+	pending, err := buffer.Pending(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].Event.Type != "OpportunityUpdate" {
+		t.Errorf("buffer after the replay: %v, expected the opportunity event", pending)
+	}
+}
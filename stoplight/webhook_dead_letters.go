@@ -0,0 +1,135 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+)
+
+const defaultWebhookMaxAttempts = 5
+
+// deadLetter counts the failed attempt in the buffered event, so the count survives restarts, and moves the event
+// to the dead letter queue with its error after max_attempts failures. It returns the error of the attempt while
+// the event must be retried
+func (s *Stoplight) deadLetter(buffer WebhookBuffer, buffered *BufferedWebhookEvent, deadLetters WebhookBuffer, failure error) error {
+	maxAttempts := defaultWebhookMaxAttempts
+	if s.config.Webhooks != nil && s.config.Webhooks.MaxAttempts > 0 {
+		maxAttempts = s.config.Webhooks.MaxAttempts
+	}
+
+	attempts := buffered.Event.Attempts + 1
+	if attempts < maxAttempts {
+		buffered.Event.Attempts = attempts
+		err := buffer.Update(buffered.Id, buffered.Event)
+		if err != nil {
+			return err
+		}
+		return failure
+	}
+
+	logging.Warnf("[%s] %s webhook event moved to the dead letter queue after %d attempts: %v",
+		s.collection.SourceID, buffered.Event.Type, attempts, failure)
+	event := *buffered.Event
+	event.Error = failure.Error()
+	event.Attempts = attempts
+	event.FailedAt = time.Now().UTC().Format(time.RFC3339)
+	return deadLetters.Append(&event)
+}
+
+// RequeueDeadLetters moves the dead letters with the ids (every dead letter if ids is empty) back to the buffer
+// to be replayed. It returns the number of requeued events
+func RequeueDeadLetters(deadLetters WebhookBuffer, buffer WebhookBuffer, ids ...string) (int, error) {
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	requeued := 0
+	skipped := 0
+	for {
+		// skipped dead letters stay at the head of the queue: read past them
+		letters, err := deadLetters.Pending(skipped + webhookReplayBatchSize)
+		if err != nil {
+			return requeued, err
+		}
+		if len(letters) <= skipped {
+			return requeued, nil
+		}
+		letters = letters[skipped:]
+
+		for _, letter := range letters {
+			if len(ids) > 0 && !wanted[letter.Id] {
+				skipped++
+				continue
+			}
+
+			event := *letter.Event
+			event.Error = ""
+			event.Attempts = 0
+			event.FailedAt = ""
+			err = buffer.Append(&event)
+			if err != nil {
+				return requeued, err
+			}
+			err = deadLetters.Remove(letter.Id)
+			if err != nil {
+				return requeued, err
+			}
+			requeued++
+		}
+	}
+}
+
+// DeadLetterHandler returns the http handler of the dead letter queue API:
+//
+//	GET ?limit=100 lists the dead letters with their payload and error
+//	POST [?id=...] requeues the dead letters (all of them without id) into the buffer to be replayed
+//	DELETE ?id=... discards the dead letters
+func DeadLetterHandler(deadLetters WebhookBuffer, buffer WebhookBuffer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids := r.URL.Query()["id"]
+
+		var response interface{}
+		var err error
+		switch r.Method {
+		case http.MethodGet:
+			limit := webhookReplayBatchSize
+			if value := r.URL.Query().Get("limit"); value != "" {
+				limit, err = strconv.Atoi(value)
+				if err != nil || limit <= 0 {
+					http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+					return
+				}
+			}
+			var letters []*BufferedWebhookEvent
+			letters, err = deadLetters.Pending(limit)
+			response = letters
+		case http.MethodPost:
+			var requeued int
+			requeued, err = RequeueDeadLetters(deadLetters, buffer, ids...)
+			response = map[string]int{"requeued": requeued}
+		case http.MethodDelete:
+			if len(ids) == 0 {
+				http.Error(w, "id is required", http.StatusBadRequest)
+				return
+			}
+			err = deadLetters.Remove(ids...)
+			response = map[string]int{"deleted": len(ids)}
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	})
+}
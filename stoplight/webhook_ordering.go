@@ -0,0 +1,119 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"time"
+)
+
+const (
+	defaultWebhookPartitions     = 8
+	defaultWebhookHandlerTimeout = 30 * time.Second
+)
+
+var (
+	errWebhookHandlerTimeout  = errors.New("webhook handler timed out")
+	errWebhookReceiverStopped = errors.New("webhook receiver is stopped")
+)
+
+// orderedWebhookEvent is an event waiting for its partition worker with the channel of its handling result
+type orderedWebhookEvent struct {
+	event  *WebhookEvent
+	result chan error
+}
+
+// orderedHandler returns a handler partitioning the events by entity id: each partition is handled by one worker
+// so the events of an entity are handled in their arrival order, while the other entities are handled concurrently.
+// The returned handler waits for the event to be handled. The workers stop when stop is closed: the events
+// received afterwards fail. The queues aren't closed as handlers may still be sending to them
+func (wc *WebhookConfig) orderedHandler(handle WebhookHandlerFunc, stop <-chan struct{}) WebhookHandlerFunc {
+	partitions := defaultWebhookPartitions
+	if wc != nil && wc.Partitions > 0 {
+		partitions = wc.Partitions
+	}
+	timeout := wc.handlerTimeout()
+
+	queues := make([]chan *orderedWebhookEvent, partitions)
+	for i := range queues {
+		queues[i] = make(chan *orderedWebhookEvent, 100)
+		go func(queue chan *orderedWebhookEvent) {
+			for {
+				select {
+				case <-stop:
+					return
+				case ordered := <-queue:
+					ordered.result <- handleWithTimeout(handle, ordered.event, timeout)
+				}
+			}
+		}(queues[i])
+	}
+
+	return func(event *WebhookEvent) error {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(wc.entityId(event)))
+		ordered := &orderedWebhookEvent{event: event, result: make(chan error, 1)}
+		select {
+		case queues[h.Sum32()%uint32(partitions)] <- ordered:
+		case <-stop:
+			return errWebhookReceiverStopped
+		}
+
+		select {
+		case err := <-ordered.result:
+			return err
+		case <-stop:
+			return errWebhookReceiverStopped
+		}
+	}
+}
+
+// handleWithTimeout handles the event, giving up after the timeout so a blocked handler doesn't hold the events
+// queued behind it. The event fails, to be redelivered, while its handler may still complete
+func handleWithTimeout(handle WebhookHandlerFunc, event *WebhookEvent, timeout time.Duration) error {
+	result := make(chan error, 1)
+	go func() {
+		result <- handle(event)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-result:
+		return err
+	case <-timer.C:
+		return errWebhookHandlerTimeout
+	}
+}
+
+// handlerTimeout returns the configured max time an event is handled or 30 seconds by default
+func (wc *WebhookConfig) handlerTimeout() time.Duration {
+	if wc != nil && wc.HandlerTimeout != "" {
+		if timeout, err := time.ParseDuration(wc.HandlerTimeout); err == nil && timeout > 0 {
+			return timeout
+		}
+	}
+	return defaultWebhookHandlerTimeout
+}
+
+// entityId returns the id of the entity (contact, opportunity, appointment...) of the webhook event:
+// the id of its routed record, or its contactId
+func (wc *WebhookConfig) entityId(event *WebhookEvent) string {
+	record := event.Payload
+	if wc != nil {
+		if route := wc.webhookRoute(event.Type); route != nil && route.RecordKey != "" {
+			if nested, ok := event.Payload[route.RecordKey].(map[string]interface{}); ok {
+				record = nested
+			}
+		}
+	}
+
+	for _, field := range []string{"id", "contactId"} {
+		if id, ok := record[field]; ok && id != nil {
+			return fmt.Sprint(id)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,55 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestOrderedHandlerTimeout(t *testing.T) {
+	stop := make(chan struct{})
+	defer close(stop)
+	blocked := make(chan struct{})
+	defer close(blocked)
+
+	wc := &WebhookConfig{Partitions: 1, HandlerTimeout: "50ms"}
+	handle := wc.orderedHandler(func(event *WebhookEvent) error {
+		if event.Type == "ContactCreate" {
+			<-blocked
+		}
+		return nil
+	}, stop)
+
+	contact := map[string]interface{}{"id": "c1"}
+	if err := handle(&WebhookEvent{Type: "ContactCreate", Payload: contact}); err != errWebhookHandlerTimeout {
+		t.Errorf("blocked handler returned %v, expected a timeout", err)
+	}
+	// the partition isn't held by the blocked handler
+	if err := handle(&WebhookEvent{Type: "ContactUpdate", Payload: contact}); err != nil {
+		t.Errorf("event queued behind the blocked one failed: %v", err)
+	}
+}
+
+func TestOrderedHandlerStop(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	stop := make(chan struct{})
+	handle := (&WebhookConfig{Partitions: 4}).orderedHandler(func(event *WebhookEvent) error { return nil }, stop)
+	if err := handle(&WebhookEvent{Type: "ContactCreate", Payload: map[string]interface{}{"id": "c1"}}); err != nil {
+		t.Fatal(err)
+	}
+	close(stop)
+
+	if err := handle(&WebhookEvent{Type: "ContactCreate", Payload: map[string]interface{}{"id": "c1"}}); err != errWebhookReceiverStopped {
+		t.Errorf("event received after stop returned %v, expected %v", err, errWebhookReceiverStopped)
+	}
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("%d goroutines after stop, expected %d", n, before)
+	}
+}
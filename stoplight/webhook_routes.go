@@ -0,0 +1,245 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WebhookRoute routes the events of a webhook event type to a collection. The record is the value of record_key
+// in the event payload or the payload itself when record_key is empty. id_key is the field of the record id
+// when it isn't id
+type WebhookRoute struct {
+	Collection string `mapstructure:"collection" json:"collection,omitempty" yaml:"collection,omitempty"`
+	RecordKey  string `mapstructure:"record_key" json:"record_key,omitempty" yaml:"record_key,omitempty"`
+	IdKey      string `mapstructure:"id_key" json:"id_key,omitempty" yaml:"id_key,omitempty"`
+}
+
+// defaultWebhookRoutes are the routes of the webhook event types which aren't configured in webhooks routes
+var defaultWebhookRoutes = map[string]*WebhookRoute{
+	"ContactCreate":                  {Collection: ContactsCollection},
+	"ContactUpdate":                  {Collection: ContactsCollection},
+	"ContactDelete":                  {Collection: ContactsCollection},
+	"ContactTagUpdate":               {Collection: ContactsCollection},
+	"ContactDndUpdate":               {Collection: ContactsCollection},
+	"OpportunityCreate":              {Collection: OpportunitiesCollection},
+	"OpportunityUpdate":              {Collection: OpportunitiesCollection},
+	"OpportunityDelete":              {Collection: OpportunitiesCollection},
+	"OpportunityStageUpdate":         {Collection: OpportunitiesCollection},
+	"OpportunityStatusUpdate":        {Collection: OpportunitiesCollection},
+	"OpportunityMonetaryValueUpdate": {Collection: OpportunitiesCollection},
+	"OpportunityAssignedToUpdate":    {Collection: OpportunitiesCollection},
+	"AppointmentCreate":              {Collection: ContactAppointmentsCollection, RecordKey: "appointment"},
+	"AppointmentUpdate":              {Collection: ContactAppointmentsCollection, RecordKey: "appointment"},
+	"AppointmentDelete":              {Collection: ContactAppointmentsCollection, RecordKey: "appointment"},
+	"InboundMessage":                 {Collection: MessagesCollection, IdKey: "messageId"},
+	"OutboundMessage":                {Collection: MessagesCollection, IdKey: "messageId"},
+	"InvoiceCreate":                  {Collection: InvoicesCollection},
+	"InvoiceUpdate":                  {Collection: InvoicesCollection},
+	"InvoiceDelete":                  {Collection: InvoicesCollection},
+	"InvoiceSent":                    {Collection: InvoicesCollection},
+	"InvoicePaid":                    {Collection: InvoicesCollection},
+	"InvoicePartiallyPaid":           {Collection: InvoicesCollection},
+	"InvoiceVoid":                    {Collection: InvoicesCollection},
+	"ProductCreate":                  {Collection: ProductsCollection},
+	"ProductUpdate":                  {Collection: ProductsCollection},
+	"ProductDelete":                  {Collection: ProductsCollection},
+}
+
+// webhookDrivers are the drivers of the routed webhook events by collection type and location. They are kept
+// so their caches (custom fields, pipelines, users, calendars...) are requested once rather than per event
+type webhookDrivers struct {
+	mutex   sync.Mutex
+	drivers map[string]*webhookDriver
+}
+
+// webhookDriver is the driver of the webhook events of a collection and location. Its caches aren't
+// goroutine-safe: events are shaped one at a time
+type webhookDriver struct {
+	mutex  sync.Mutex
+	driver *Stoplight
+}
+
+// webhookDriver returns the driver of the webhook events of the collection type and location
+func (s *Stoplight) webhookDriver(collectionType string, locationId string) *webhookDriver {
+	newDriver := func() *webhookDriver {
+		driver := s.forCollection(collectionType)
+		if locationId != driver.config.LocationId {
+			config := *driver.config
+			config.LocationId = locationId
+			collection := *driver.collection
+			driver = &Stoplight{
+				client:        driver.client,
+				ctx:           driver.ctx,
+				config:        &config,
+				collection:    &collection,
+				transformers:  driver.transformers,
+				qualityReport: driver.qualityReport,
+			}
+		}
+		return &webhookDriver{driver: driver}
+	}
+	// drivers which weren't created by NewStoplight don't keep the webhook drivers
+	if s.webhookDrivers == nil {
+		return newDriver()
+	}
+
+	s.webhookDrivers.mutex.Lock()
+	defer s.webhookDrivers.mutex.Unlock()
+	key := collectionType + "/" + locationId
+	wd, ok := s.webhookDrivers.drivers[key]
+	if !ok {
+		wd = newDriver()
+		s.webhookDrivers.drivers[key] = wd
+	}
+	return wd
+}
+
+// shape applies the post-fetch steps of the polled records of the collection to the routed records:
+// raw payload, custom fields pivot, normalizations, enrichments and surrogate keys
+func (wd *webhookDriver) shape(objects []map[string]interface{}) error {
+	wd.mutex.Lock()
+	defer wd.mutex.Unlock()
+
+	s := wd.driver
+	s.keepRawPayloads(objects)
+	var err error
+	switch s.collection.Type {
+	case ContactsCollection:
+		err = s.shapeContacts(objects)
+	case OpportunitiesCollection:
+		err = s.shapeOpportunities(objects)
+	case ContactAppointmentsCollection:
+		err = s.shapeAppointments(objects)
+	}
+	if err != nil {
+		return err
+	}
+	s.addSurrogateKeys(objects)
+
+	return nil
+}
+
+// webhookRoute returns the route of the event type or nil if events of this type aren't routed
+func (wc *WebhookConfig) webhookRoute(eventType string) *WebhookRoute {
+	if route, ok := wc.Routes[eventType]; ok {
+		return route
+	}
+	return defaultWebhookRoutes[eventType]
+}
+
+// RouteWebhookEvent converts the webhook event into the records of its collection, shaped like the polled ones:
+// the record goes through the post-fetch steps of its collection (custom fields pivot, normalizations,
+// enrichments...) and the collection pipeline (filters, masking, renames, transformers...) and gets
+// the _change_type of the event, the _location_id of the event in multi-location mode and the _synced_at and
+// _sync_run_id of a sync run of its own. Delete events are converted into tombstones. It returns an empty
+// collection for events which aren't routed. In hybrid mode, the version of the record is recorded as loaded
+// when it is routed: use ReplayWebhookEvents to record it once the load succeeded
+func (s *Stoplight) RouteWebhookEvent(event *WebhookEvent) (string, []map[string]interface{}, error) {
+	driver, objects, pending, err := s.routeWebhookEvent(event)
+	if err != nil || driver == nil {
+		return "", nil, err
+	}
+
+	err = pending.commit()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return driver.collection.Type, objects, nil
+}
+
+// routeWebhookEvent returns the driver of the collection of the webhook event, its processed records and,
+// in hybrid mode, their pending versions, or a nil driver if events of this type aren't routed
+func (s *Stoplight) routeWebhookEvent(event *WebhookEvent) (*Stoplight, []map[string]interface{}, *pendingVersions, error) {
+	if s.config.Webhooks == nil {
+		return nil, nil, nil, errors.New("webhooks aren't configured")
+	}
+	event, err := adaptWebhookEvent(event)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	route := s.config.Webhooks.webhookRoute(event.Type)
+	if route == nil {
+		return nil, nil, nil, nil
+	}
+
+	record := event.Payload
+	if route.RecordKey != "" {
+		nested, _ := event.Payload[route.RecordKey].(map[string]interface{})
+		switch {
+		case nested != nil:
+			record = nested
+		case s.config.Webhooks.UnknownFields == UnknownFieldsStrict:
+			return nil, nil, nil, fmt.Errorf("%s webhook event doesn't have %s", event.Type, route.RecordKey)
+		}
+	}
+	if route.IdKey != "" {
+		if _, ok := record["id"]; !ok {
+			withId := make(map[string]interface{}, len(record)+1)
+			for k, v := range record {
+				withId[k] = v
+			}
+			withId["id"] = record[route.IdKey]
+			record = withId
+		}
+	}
+	record, err = s.config.Webhooks.knownFields(route.Collection, record)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%s webhook event: %v", event.Type, err)
+	}
+
+	locationId := event.LocationId
+	if locationId == "" {
+		locationId = s.config.LocationId
+	}
+	wd := s.webhookDriver(route.Collection, locationId)
+	driver := wd.driver
+
+	var object map[string]interface{}
+	switch {
+	case strings.HasSuffix(event.Type, "Delete"):
+		object = tombstone(record["id"], time.Now())
+	default:
+		object = make(map[string]interface{}, len(record))
+		for k, v := range record {
+			if !webhookEnvelopeFields[k] {
+				object[k] = v
+			}
+		}
+		err = wd.shape([]map[string]interface{}{object})
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("%s webhook event: %v", event.Type, err)
+		}
+		object[changeTypeField] = ChangeUpdate
+		if strings.HasSuffix(event.Type, "Create") {
+			object[changeTypeField] = ChangeInsert
+		}
+	}
+
+	objects := []map[string]interface{}{object}
+	var pending *pendingVersions
+	if driver.hybrid() {
+		var err error
+		// the versions of the polled records of the event location
+		objects, pending, err = driver.skipLoadedVersions(objects, locationId)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	objects, err = driver.process(objects)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	// the driver of the event location
+	driver.addLocationIds(objects)
+	// every event is loaded as its own sync run
+	addSyncMetadata(objects, time.Now(), newSyncRunId())
+
+	return driver, objects, pending, nil
+}
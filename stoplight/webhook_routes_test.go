@@ -0,0 +1,76 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+func TestWebhookRecordsShapedLikePolledOnes(t *testing.T) {
+	s := &Stoplight{
+		config:         &StoplightConfig{Currency: "EUR", RawPayload: true, Webhooks: &WebhookConfig{}},
+		collection:     &base.Collection{SourceID: "source", Type: ContactsCollection},
+		webhookDrivers: &webhookDrivers{drivers: map[string]*webhookDriver{}},
+	}
+	event := &WebhookEvent{Type: "OpportunityUpdate", LocationId: "location1", Payload: map[string]interface{}{
+		"id":            "o1",
+		"monetaryValue": "12.5",
+	}}
+
+	collection, objects, err := s.RouteWebhookEvent(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if collection != OpportunitiesCollection || len(objects) != 1 {
+		t.Fatalf("routed %d %s objects, expected 1 opportunity", len(objects), collection)
+	}
+	opportunity := objects[0]
+	if opportunity["monetaryValue"] != 12.5 || opportunity["currency"] != "EUR" {
+		t.Errorf("monetary value %v %v, expected the normalized 12.5 EUR", opportunity["monetaryValue"], opportunity["currency"])
+	}
+	if opportunity[rawField] != `{"id":"o1","monetaryValue":"12.5"}` {
+		t.Errorf("raw payload %v, expected the webhook record", opportunity[rawField])
+	}
+
+	if s.webhookDriver(OpportunitiesCollection, "location1") != s.webhookDriver(OpportunitiesCollection, "location1") {
+		t.Error("webhook drivers aren't reused across events")
+	}
+	if s.webhookDriver(OpportunitiesCollection, "location2").driver.config.LocationId != "location2" {
+		t.Error("webhook driver of location2 doesn't request location2")
+	}
+}
+
+func TestWebhookRecordsSyncMetadata(t *testing.T) {
+	s := &Stoplight{
+		config:     &StoplightConfig{Webhooks: &WebhookConfig{}},
+		collection: &base.Collection{SourceID: "source", Type: ProductsCollection},
+	}
+
+	_, objects, err := s.RouteWebhookEvent(&WebhookEvent{Type: "ProductUpdate", Payload: map[string]interface{}{"_id": "p1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 1 || objects[0][syncedAtField] == nil || objects[0][syncRunIdField] == nil {
+		t.Errorf("routed %v, expected the sync metadata", objects)
+	}
+}
+
+func TestWebhookRecordsLocationId(t *testing.T) {
+	s := &Stoplight{
+		config:     &StoplightConfig{LocationIds: []string{"location1", "location2"}, Webhooks: &WebhookConfig{}},
+		collection: &base.Collection{SourceID: "source", Type: ProductsCollection},
+	}
+
+	for _, eventType := range []string{"ProductUpdate", "ProductDelete"} {
+		event := &WebhookEvent{Type: eventType, LocationId: "location2", Payload: map[string]interface{}{"_id": "p1", "id": "p1"}}
+		_, objects, err := s.RouteWebhookEvent(event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(objects) != 1 || objects[0][locationIdField] != "location2" {
+			t.Errorf("routed %s %v, expected the location2 _location_id", eventType, objects)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	UnknownFieldsPassthrough = "passthrough"
+	UnknownFieldsDrop        = "drop"
+	UnknownFieldsStrict      = "strict"
+)
+
+// WebhookAdapter normalizes the payload of a webhook event of a given payload version into the current shape
+type WebhookAdapter func(event *WebhookEvent) (*WebhookEvent, error)
+
+// webhookEnvelopeFields are the fields of the webhook payloads describing the event rather than the record
+var webhookEnvelopeFields = map[string]bool{"type": true, "version": true, "webhookId": true, "timestamp": true}
+
+var (
+	webhookAdaptersMutex sync.RWMutex
+	webhookAdapters      = map[string]WebhookAdapter{}
+
+	schemaPropertiesMutex sync.Mutex
+	schemaProperties      = map[string]map[string]bool{}
+)
+
+// RegisterWebhookAdapter registers the adapter of the webhook payloads of the version (the payload version field).
+// Payloads of versions without adapter are routed as is
+func RegisterWebhookAdapter(version string, adapter WebhookAdapter) {
+	webhookAdaptersMutex.Lock()
+	defer webhookAdaptersMutex.Unlock()
+
+	webhookAdapters[version] = adapter
+}
+
+// adaptWebhookEvent applies the adapter of the payload version of the event
+func adaptWebhookEvent(event *WebhookEvent) (*WebhookEvent, error) {
+	version, ok := event.Payload["version"]
+	if !ok || version == nil {
+		return event, nil
+	}
+
+	webhookAdaptersMutex.RLock()
+	adapter, ok := webhookAdapters[fmt.Sprint(version)]
+	webhookAdaptersMutex.RUnlock()
+	if !ok {
+		return event, nil
+	}
+
+	adapted, err := adapter(event)
+	if err != nil {
+		return nil, fmt.Errorf("Error adapting %s webhook payload version %v: %v", event.Type, version, err)
+	}
+	return adapted, nil
+}
+
+// knownFields applies the unknown_fields mode to the fields of the record which aren't in the collection schema:
+// they are kept in passthrough mode (default), dropped in drop mode and fail the event in strict mode.
+// Records of collections without schema are kept as is
+func (wc *WebhookConfig) knownFields(collection string, record map[string]interface{}) (map[string]interface{}, error) {
+	if wc.UnknownFields == "" || wc.UnknownFields == UnknownFieldsPassthrough {
+		return record, nil
+	}
+
+	properties, err := collectionProperties(collection)
+	if err != nil || properties == nil {
+		return record, err
+	}
+
+	known := make(map[string]interface{}, len(record))
+	var unknown []string
+	for field, value := range record {
+		if properties[field] || webhookEnvelopeFields[field] {
+			known[field] = value
+		} else {
+			unknown = append(unknown, field)
+		}
+	}
+
+	if len(unknown) > 0 && wc.UnknownFields == UnknownFieldsStrict {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown %s fields: %s", collection, strings.Join(unknown, ", "))
+	}
+	return known, nil
+}
+
+// collectionProperties returns the properties of the embedded schema of the collection type or nil if the collection
+// doesn't have one
+func collectionProperties(collectionType string) (map[string]bool, error) {
+	schemaPropertiesMutex.Lock()
+	defer schemaPropertiesMutex.Unlock()
+
+	if properties, ok := schemaProperties[collectionType]; ok {
+		return properties, nil
+	}
+
+	b, err := schemasFS.ReadFile("schemas/" + collectionType + ".json")
+	if err != nil {
+		schemaProperties[collectionType] = nil
+		return nil, nil
+	}
+
+	var schema struct {
+		Properties map[string]interface{} `json:"properties"`
+	}
+	err = json.Unmarshal(b, &schema)
+	if err != nil {
+		return nil, err
+	}
+
+	properties := make(map[string]bool, len(schema.Properties))
+	for property := range schema.Properties {
+		properties[property] = true
+	}
+	schemaProperties[collectionType] = properties
+
+	return properties, nil
+}
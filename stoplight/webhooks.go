@@ -0,0 +1,378 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+)
+
+const (
+	defaultWebhookSignatureHeader = "X-Wh-Signature"
+	defaultWebhookTimestampHeader = "X-Wh-Timestamp"
+	defaultWebhookTolerance       = 5 * time.Minute
+	defaultWebhookDedupeTtl       = time.Hour
+	defaultWebhookDedupeSize      = 10000
+	defaultWebhookBatchWindow     = time.Second
+
+	maxWebhookBodySize = 1 << 20
+)
+
+var (
+	errInvalidWebhookSignature = errors.New("invalid webhook signature")
+	errExpiredWebhookTimestamp = errors.New("webhook timestamp is missing or outside of the tolerance")
+)
+
+// WebhookConfig is the configuration of the webhook receiver. HighLevel webhooks are signed with the HighLevel
+// RSA key: with public_key (the PEM public key published by HighLevel), the signature header is the base64
+// RSA-SHA256 signature of the body. HighLevel doesn't send a signed timestamp: replays are rejected by the event
+// ids dedupe only. Webhooks relayed by a proxy can be signed with a shared secret instead: the signature header is
+// the hex HMAC-SHA256 of "<timestamp>.<body>" where timestamp is the unix time (seconds) of the timestamp header,
+// and requests signed more than tolerance ago (or in the future) are rejected as replays
+type WebhookConfig struct {
+	PublicKey       string                   `mapstructure:"public_key" json:"public_key,omitempty" yaml:"public_key,omitempty"`
+	Secret          string                   `mapstructure:"secret" json:"secret,omitempty" yaml:"secret,omitempty"`
+	SignatureHeader string                   `mapstructure:"signature_header" json:"signature_header,omitempty" yaml:"signature_header,omitempty"`
+	TimestampHeader string                   `mapstructure:"timestamp_header" json:"timestamp_header,omitempty" yaml:"timestamp_header,omitempty"`
+	Tolerance       string                   `mapstructure:"tolerance" json:"tolerance,omitempty" yaml:"tolerance,omitempty"`
+	Routes          map[string]*WebhookRoute `mapstructure:"routes" json:"routes,omitempty" yaml:"routes,omitempty"`
+	Buffer          *WebhookBufferConfig     `mapstructure:"buffer" json:"buffer,omitempty" yaml:"buffer,omitempty"`
+	Hybrid          bool                     `mapstructure:"hybrid" json:"hybrid,omitempty" yaml:"hybrid,omitempty"`
+	MaxAttempts     int                      `mapstructure:"max_attempts" json:"max_attempts,omitempty" yaml:"max_attempts,omitempty"`
+	DeadLetter      *WebhookBufferConfig     `mapstructure:"dead_letter" json:"dead_letter,omitempty" yaml:"dead_letter,omitempty"`
+	DedupeTtl       string                   `mapstructure:"dedupe_ttl" json:"dedupe_ttl,omitempty" yaml:"dedupe_ttl,omitempty"`
+	DedupeSize      int                      `mapstructure:"dedupe_size" json:"dedupe_size,omitempty" yaml:"dedupe_size,omitempty"`
+	Partitions      int                      `mapstructure:"partitions" json:"partitions,omitempty" yaml:"partitions,omitempty"`
+	HandlerTimeout  string                   `mapstructure:"handler_timeout" json:"handler_timeout,omitempty" yaml:"handler_timeout,omitempty"`
+	UnknownFields   string                   `mapstructure:"unknown_fields" json:"unknown_fields,omitempty" yaml:"unknown_fields,omitempty"`
+	BatchSize       int                      `mapstructure:"batch_size" json:"batch_size,omitempty" yaml:"batch_size,omitempty"`
+	BatchWindow     string                   `mapstructure:"batch_window" json:"batch_window,omitempty" yaml:"batch_window,omitempty"`
+}
+
+// Validate returns an error if the webhook configuration is invalid
+func (wc *WebhookConfig) Validate() error {
+	if wc.PublicKey == "" && wc.Secret == "" {
+		return errors.New("Stoplight webhooks public_key or secret is required")
+	}
+
+	if wc.PublicKey != "" {
+		if _, err := wc.publicKey(); err != nil {
+			return fmt.Errorf("Stoplight webhooks public_key is invalid: %v", err)
+		}
+	}
+
+	tolerance, err := wc.tolerance()
+	if err != nil {
+		return fmt.Errorf("Stoplight webhooks tolerance is invalid: %v", err)
+	}
+	if tolerance <= 0 {
+		return errors.New("Stoplight webhooks tolerance must be positive")
+	}
+
+	if wc.Buffer != nil {
+		err := wc.Buffer.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := wc.dedupeTtl(); err != nil {
+		return fmt.Errorf("Stoplight webhooks dedupe_ttl is invalid: %v", err)
+	}
+
+	if wc.DedupeSize < 0 {
+		return errors.New("Stoplight webhooks dedupe_size must be positive")
+	}
+
+	switch wc.UnknownFields {
+	case "", UnknownFieldsPassthrough, UnknownFieldsDrop, UnknownFieldsStrict:
+	default:
+		return fmt.Errorf("Stoplight webhooks unknown_fields %q is not supported: use passthrough, drop or strict", wc.UnknownFields)
+	}
+
+	if wc.BatchSize < 0 {
+		return errors.New("Stoplight webhooks batch_size must be positive")
+	}
+
+	if wc.BatchWindow != "" {
+		if _, err := time.ParseDuration(wc.BatchWindow); err != nil {
+			return fmt.Errorf("Stoplight webhooks batch_window is invalid: %v", err)
+		}
+	}
+
+	if wc.Partitions < 0 {
+		return errors.New("Stoplight webhooks partitions must be positive")
+	}
+
+	if wc.HandlerTimeout != "" {
+		timeout, err := time.ParseDuration(wc.HandlerTimeout)
+		if err != nil {
+			return fmt.Errorf("Stoplight webhooks handler_timeout is invalid: %v", err)
+		}
+		if timeout <= 0 {
+			return errors.New("Stoplight webhooks handler_timeout must be positive")
+		}
+	}
+
+	if wc.MaxAttempts < 0 {
+		return errors.New("Stoplight webhooks max_attempts must be positive")
+	}
+
+	if wc.DeadLetter != nil {
+		err := wc.DeadLetter.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	for eventType, route := range wc.Routes {
+		if route == nil || route.Collection == "" {
+			return fmt.Errorf("Stoplight webhooks route collection of %s events is required", eventType)
+		}
+	}
+
+	return nil
+}
+
+// signatureHeader returns the configured signature header or X-Wh-Signature by default
+func (wc *WebhookConfig) signatureHeader() string {
+	if wc.SignatureHeader == "" {
+		return defaultWebhookSignatureHeader
+	}
+	return wc.SignatureHeader
+}
+
+// timestampHeader returns the configured timestamp header or X-Wh-Timestamp by default
+func (wc *WebhookConfig) timestampHeader() string {
+	if wc.TimestampHeader == "" {
+		return defaultWebhookTimestampHeader
+	}
+	return wc.TimestampHeader
+}
+
+// tolerance returns the configured tolerance of the webhooks timestamp or 5 minutes by default
+func (wc *WebhookConfig) tolerance() (time.Duration, error) {
+	if wc.Tolerance == "" {
+		return defaultWebhookTolerance, nil
+	}
+	return time.ParseDuration(wc.Tolerance)
+}
+
+// WebhookEvent is a HighLevel webhook event: its id (webhookId), type (e.g. ContactCreate), location and payload.
+// Buffered events keep their number of failed replays, dead letters also the error of their last attempt
+type WebhookEvent struct {
+	Id         string                 `json:"id,omitempty"`
+	Type       string                 `json:"type"`
+	LocationId string                 `json:"location_id"`
+	Payload    map[string]interface{} `json:"payload"`
+	Error      string                 `json:"error,omitempty"`
+	Attempts   int                    `json:"attempts,omitempty"`
+	FailedAt   string                 `json:"failed_at,omitempty"`
+}
+
+// WebhookHandlerFunc handles the verified webhook events
+type WebhookHandlerFunc func(event *WebhookEvent) error
+
+// WebhookHandler returns the http handler receiving the webhook events of the driver. Requests with an invalid
+// signature or a replayed timestamp are rejected with 401 before the event is handled. Redelivered events
+// (same id handled within dedupe_ttl) are acknowledged without being handled again, redeliveries of an event
+// still being handled are rejected with 409 so they are retried if it fails. Events of the same entity
+// (contact, opportunity...) are handled one at a time in their arrival order, for at most handler_timeout.
+// The handler workers stop when stop is closed
+func (s *Stoplight) WebhookHandler(handle WebhookHandlerFunc, stop <-chan struct{}) http.Handler {
+	ttl, _ := s.config.Webhooks.dedupeTtl()
+	seen := newSeenEventIds(ttl, s.config.Webhooks.dedupeSize())
+	handle = s.config.Webhooks.orderedHandler(handle, stop)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		// errors are logged but not returned: the endpoint is public
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodySize))
+		if err != nil {
+			logging.Warnf("[%s] rejected webhook: %v", s.collection.SourceID, err)
+			http.Error(w, "webhook body is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		err = s.config.Webhooks.verify(r.Header, body, time.Now())
+		if err != nil {
+			logging.Warnf("[%s] rejected webhook: %v", s.collection.SourceID, err)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		payload := map[string]interface{}{}
+		err = json.Unmarshal(body, &payload)
+		if err != nil {
+			logging.Warnf("[%s] rejected webhook: invalid JSON payload: %v", s.collection.SourceID, err)
+			http.Error(w, "webhook payload isn't a JSON object", http.StatusBadRequest)
+			return
+		}
+
+		event := &WebhookEvent{Payload: payload}
+		event.Id, _ = payload["webhookId"].(string)
+		event.Type, _ = payload["type"].(string)
+		event.LocationId, _ = payload["locationId"].(string)
+
+		if event.Id != "" {
+			switch seen.start(event.Id, time.Now()) {
+			case eventSeen:
+				logging.Infof("[%s] skipped redelivered %s webhook %s", s.collection.SourceID, event.Type, event.Id)
+				w.WriteHeader(http.StatusOK)
+				return
+			case eventInFlight:
+				http.Error(w, "webhook "+event.Id+" is being handled", http.StatusConflict)
+				return
+			}
+		}
+
+		err = handle(event)
+		if event.Id != "" {
+			seen.finish(event.Id, err == nil, time.Now())
+		}
+		if err != nil {
+			logging.Warnf("[%s] error handling %s webhook: %v", s.collection.SourceID, event.Type, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// dedupeTtl returns the configured time the webhook event ids are remembered or 1 hour by default
+func (wc *WebhookConfig) dedupeTtl() (time.Duration, error) {
+	if wc == nil || wc.DedupeTtl == "" {
+		return defaultWebhookDedupeTtl, nil
+	}
+	return time.ParseDuration(wc.DedupeTtl)
+}
+
+// dedupeSize returns the configured max number of remembered webhook event ids or 10000 by default
+func (wc *WebhookConfig) dedupeSize() int {
+	if wc == nil || wc.DedupeSize == 0 {
+		return defaultWebhookDedupeSize
+	}
+	return wc.DedupeSize
+}
+
+// batchSize returns the configured max number of webhook events loaded together or 100 by default
+func (wc *WebhookConfig) batchSize() int {
+	if wc == nil || wc.BatchSize == 0 {
+		return webhookReplayBatchSize
+	}
+	return wc.BatchSize
+}
+
+// batchWindow returns the configured time the webhook events are buffered before being loaded together
+// or 1 second by default
+func (wc *WebhookConfig) batchWindow() time.Duration {
+	if wc != nil && wc.BatchWindow != "" {
+		if window, err := time.ParseDuration(wc.BatchWindow); err == nil && window > 0 {
+			return window
+		}
+	}
+	return defaultWebhookBatchWindow
+}
+
+// verify checks the signature of the webhook body: the HighLevel RSA signature with public_key, or the HMAC
+// signature of the timestamp and body, rejecting timestamps outside of the tolerance, with secret
+func (wc *WebhookConfig) verify(header http.Header, body []byte, now time.Time) error {
+	if wc == nil {
+		return errors.New("webhooks aren't configured")
+	}
+	if wc.PublicKey != "" {
+		return wc.verifyRsa(header, body)
+	}
+
+	tolerance, err := wc.tolerance()
+	if err != nil {
+		return err
+	}
+
+	timestamp := header.Get(wc.timestampHeader())
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errExpiredWebhookTimestamp
+	}
+	age := now.Sub(time.Unix(seconds, 0))
+	if age > tolerance || age < -tolerance {
+		return errExpiredWebhookTimestamp
+	}
+
+	signature, err := hex.DecodeString(strings.TrimPrefix(header.Get(wc.signatureHeader()), "sha256="))
+	if err != nil {
+		return errInvalidWebhookSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(wc.Secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return errInvalidWebhookSignature
+	}
+
+	return nil
+}
+
+// verifyRsa checks the HighLevel signature of the webhook body: the base64 RSA-SHA256 (PKCS #1 v1.5) signature
+// of the body with the HighLevel private key
+func (wc *WebhookConfig) verifyRsa(header http.Header, body []byte) error {
+	key, err := wc.publicKey()
+	if err != nil {
+		return err
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(header.Get(wc.signatureHeader()))
+	if err != nil || len(signature) == 0 {
+		return errInvalidWebhookSignature
+	}
+
+	digest := sha256.Sum256(body)
+	if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) != nil {
+		return errInvalidWebhookSignature
+	}
+
+	return nil
+}
+
+// publicKey parses the PEM (PKIX or PKCS #1) RSA public key of the webhooks signatures
+func (wc *WebhookConfig) publicKey() (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(wc.PublicKey))
+	if block == nil {
+		return nil, errors.New("not a PEM public key")
+	}
+
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%T public key isn't an RSA key", key)
+	}
+	return rsaKey, nil
+}
@@ -0,0 +1,216 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+const testWebhookSecret = "secret"
+
+// signedWebhookRequest returns a webhook request of the body signed with the secret at the time
+func signedWebhookRequest(body string, secret string, at time.Time) *http.Request {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + body))
+
+	r := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(body))
+	r.Header.Set(defaultWebhookTimestampHeader, timestamp)
+	r.Header.Set(defaultWebhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	return r
+}
+
+func TestWebhookRedeliveryWhileInFlight(t *testing.T) {
+	s := &Stoplight{
+		config:     &StoplightConfig{Webhooks: &WebhookConfig{Secret: testWebhookSecret}},
+		collection: &base.Collection{SourceID: "source"},
+	}
+
+	started := make(chan struct{})
+	release := make(chan error)
+	var handled int32
+	stop := make(chan struct{})
+	defer close(stop)
+	handler := s.WebhookHandler(func(event *WebhookEvent) error {
+		if atomic.AddInt32(&handled, 1) == 1 {
+			close(started)
+			return <-release
+		}
+		return nil
+	}, stop)
+
+	body := `{"webhookId": "w1", "type": "ContactUpdate", "id": "c1"}`
+	deliver := func() int {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, signedWebhookRequest(body, testWebhookSecret, time.Now()))
+		return w.Code
+	}
+
+	first := make(chan int)
+	go func() { first <- deliver() }()
+	<-started
+
+	if code := deliver(); code != http.StatusConflict {
+		t.Errorf("redelivery while the event is handled responded %d, expected %d", code, http.StatusConflict)
+	}
+
+	release <- errors.New("destination is down")
+	if code := <-first; code != http.StatusInternalServerError {
+		t.Errorf("failed event responded %d, expected %d", code, http.StatusInternalServerError)
+	}
+
+	if code := deliver(); code != http.StatusOK {
+		t.Errorf("redelivery of the failed event responded %d, expected %d", code, http.StatusOK)
+	}
+	if code := deliver(); code != http.StatusOK {
+		t.Errorf("redelivery of the handled event responded %d, expected %d", code, http.StatusOK)
+	}
+	if n := atomic.LoadInt32(&handled); n != 2 {
+		t.Errorf("event handled %d times, expected 2", n)
+	}
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	now := time.Unix(1677664800, 0)
+	body := `{"type":"ContactCreate","id":"c1"}`
+	wc := &WebhookConfig{Secret: testWebhookSecret}
+
+	tests := []struct {
+		name   string
+		header func(r *http.Request)
+		err    error
+	}{
+		{"valid", func(r *http.Request) {}, nil},
+		{"without sha256 prefix", func(r *http.Request) {
+			r.Header.Set(defaultWebhookSignatureHeader, strings.TrimPrefix(r.Header.Get(defaultWebhookSignatureHeader), "sha256="))
+		}, nil},
+		{"bad hex", func(r *http.Request) {
+			r.Header.Set(defaultWebhookSignatureHeader, "sha256=not-hex")
+		}, errInvalidWebhookSignature},
+		{"wrong secret", func(r *http.Request) {
+			r.Header.Set(defaultWebhookSignatureHeader, signedWebhookRequest(body, "other", now).Header.Get(defaultWebhookSignatureHeader))
+		}, errInvalidWebhookSignature},
+		{"missing signature", func(r *http.Request) {
+			r.Header.Del(defaultWebhookSignatureHeader)
+		}, errInvalidWebhookSignature},
+		{"missing timestamp", func(r *http.Request) {
+			r.Header.Del(defaultWebhookTimestampHeader)
+		}, errExpiredWebhookTimestamp},
+		{"expired timestamp", func(r *http.Request) {
+			*r = *signedWebhookRequest(body, testWebhookSecret, now.Add(-defaultWebhookTolerance-time.Second))
+		}, errExpiredWebhookTimestamp},
+		{"future timestamp", func(r *http.Request) {
+			*r = *signedWebhookRequest(body, testWebhookSecret, now.Add(defaultWebhookTolerance+time.Second))
+		}, errExpiredWebhookTimestamp},
+		{"timestamp of another signature", func(r *http.Request) {
+			r.Header.Set(defaultWebhookTimestampHeader, strconv.FormatInt(now.Unix()-1, 10))
+		}, errInvalidWebhookSignature},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := signedWebhookRequest(body, testWebhookSecret, now)
+			test.header(r)
+			err := wc.verify(r.Header, []byte(body), now)
+			if err != test.err {
+				t.Errorf("verify returned %v, expected %v", err, test.err)
+			}
+		})
+	}
+}
+
+func TestVerifyHighLevelSignature(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wc := &WebhookConfig{PublicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey}))}
+	if err := wc.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	body := `{"type":"ContactCreate","id":"c1"}`
+	sign := func(body string) string {
+		digest := sha256.Sum256([]byte(body))
+		signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.StdEncoding.EncodeToString(signature)
+	}
+
+	tests := []struct {
+		name      string
+		signature string
+		err       error
+	}{
+		{"valid", sign(body), nil},
+		{"signature of another body", sign(`{"type":"ContactDelete","id":"c1"}`), errInvalidWebhookSignature},
+		{"bad base64", "not base64!", errInvalidWebhookSignature},
+		{"missing signature", "", errInvalidWebhookSignature},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			header := http.Header{}
+			header.Set(defaultWebhookSignatureHeader, test.signature)
+			// HighLevel doesn't send a timestamp
+			err := wc.verify(header, []byte(body), time.Now())
+			if err != test.err {
+				t.Errorf("verify returned %v, expected %v", err, test.err)
+			}
+		})
+	}
+}
+
+func TestValidateWebhookTolerance(t *testing.T) {
+	for _, tolerance := range []string{"0s", "-1m"} {
+		wc := &WebhookConfig{Secret: testWebhookSecret, Tolerance: tolerance}
+		if wc.Validate() == nil {
+			t.Errorf("tolerance %s is valid", tolerance)
+		}
+	}
+}
+
+func TestWebhookErrorsArentEchoed(t *testing.T) {
+	s := &Stoplight{
+		config:     &StoplightConfig{Webhooks: &WebhookConfig{Secret: testWebhookSecret}},
+		collection: &base.Collection{SourceID: "source"},
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	handler := s.WebhookHandler(func(event *WebhookEvent) error {
+		return errors.New("pq: connection refused to 10.0.0.1")
+	}, stop)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, signedWebhookRequest(`{"type":"ContactCreate","webhookId":"w1"}`, testWebhookSecret, time.Now()))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("responded %d, expected 500", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "10.0.0.1") {
+		t.Errorf("response %q echoes the handler error", w.Body.String())
+	}
+}
@@ -0,0 +1,147 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/jitsucom/jitsu/server/drivers/base"
+)
+
+const (
+	FormatNdjson = "ndjson"
+	FormatCsv    = "csv"
+)
+
+// RecordWriter writes the records of a collection to a file in an output format
+type RecordWriter interface {
+	// Write writes the objects
+	Write(objects []map[string]interface{}) error
+	// Close flushes the written objects. It doesn't close the underlying writer
+	Close() error
+}
+
+// writerLoader is the ObjectsLoader writing the loaded objects with a RecordWriter
+type writerLoader struct {
+	writer RecordWriter
+}
+
+// NewWriterLoader returns an ObjectsLoader writing the loaded objects with the record writer
+func NewWriterLoader(writer RecordWriter) base.ObjectsLoader {
+	return &writerLoader{writer: writer}
+}
+
+func (wl *writerLoader) Load(objects []map[string]interface{}, pos int, total int, percent int) error {
+	return wl.writer.Write(objects)
+}
+
+// ndjsonWriter writes the records as newline delimited JSON
+type ndjsonWriter struct {
+	buffered *bufio.Writer
+	encoder  *json.Encoder
+}
+
+// NewNdjsonWriter returns a record writer writing one JSON object per line
+func NewNdjsonWriter(w io.Writer) RecordWriter {
+	buffered := bufio.NewWriter(w)
+	return &ndjsonWriter{buffered: buffered, encoder: json.NewEncoder(buffered)}
+}
+
+func (nw *ndjsonWriter) Write(objects []map[string]interface{}) error {
+	for _, object := range objects {
+		err := nw.encoder.Encode(object)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (nw *ndjsonWriter) Close() error {
+	return nw.buffered.Flush()
+}
+
+// csvWriter writes the records as CSV with a header row. Columns of every record must be known before writing
+// the header: records are kept in memory until the writer is closed
+type csvWriter struct {
+	writer    *csv.Writer
+	separator string
+	objects   []map[string]interface{}
+}
+
+// NewCsvWriter returns a record writer writing CSV with the delimiter (',' if 0). Nested objects are flattened
+// into columns named with the flatten separator, arrays are written as JSON
+func NewCsvWriter(w io.Writer, delimiter rune, separator string) RecordWriter {
+	writer := csv.NewWriter(w)
+	if delimiter != 0 {
+		writer.Comma = delimiter
+	}
+	if separator == "" {
+		separator = defaultFlattenSeparator
+	}
+	return &csvWriter{writer: writer, separator: separator}
+}
+
+func (cw *csvWriter) Write(objects []map[string]interface{}) error {
+	for _, object := range objects {
+		cw.objects = append(cw.objects, flattenObject(object, cw.separator, 0))
+	}
+	return nil
+}
+
+func (cw *csvWriter) Close() error {
+	columnsSet := map[string]bool{}
+	for _, object := range cw.objects {
+		for column := range object {
+			columnsSet[column] = true
+		}
+	}
+	columns := make([]string, 0, len(columnsSet))
+	for column := range columnsSet {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	err := cw.writer.Write(columns)
+	if err != nil {
+		return err
+	}
+
+	row := make([]string, len(columns))
+	for _, object := range cw.objects {
+		for i, column := range columns {
+			row[i], err = csvValue(object[column])
+			if err != nil {
+				return err
+			}
+		}
+		err = cw.writer.Write(row)
+		if err != nil {
+			return err
+		}
+	}
+	cw.objects = nil
+
+	cw.writer.Flush()
+	return cw.writer.Error()
+}
+
+// csvValue returns the CSV cell of the value: empty for null, JSON for arrays and objects
+func csvValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []interface{}, map[string]interface{}:
+		b, err := json.Marshal(v)
+		return string(b), err
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
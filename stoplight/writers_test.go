@@ -0,0 +1,49 @@
+/* Copyright (C) 2023 Vivien Roggero LLC - All Rights Reserved
+ */
+package stoplight
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNdjsonWriter(t *testing.T) {
+	var buffer bytes.Buffer
+	writer := NewNdjsonWriter(&buffer)
+	err := NewWriterLoader(writer).Load([]map[string]interface{}{{"id": "c1"}, {"id": "c2", "tags": []interface{}{"vip"}}}, 0, 1, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\"id\":\"c1\"}\n{\"id\":\"c2\",\"tags\":[\"vip\"]}\n"
+	if buffer.String() != expected {
+		t.Errorf("wrote %q, expected %q", buffer.String(), expected)
+	}
+}
+
+func TestCsvWriter(t *testing.T) {
+	var buffer bytes.Buffer
+	writer := NewCsvWriter(&buffer, ';', "")
+	err := writer.Write([]map[string]interface{}{
+		{"id": "c1", "contact": map[string]interface{}{"name": "John"}, "tags": []interface{}{"a", "b"}},
+		{"id": "c2", "amount": 12.5, "email": nil},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "amount;contact_name;email;id;tags\n" +
+		";John;;c1;\"[\"\"a\"\",\"\"b\"\"]\"\n" +
+		"12.5;;;c2;\n"
+	if buffer.String() != expected {
+		t.Errorf("wrote %q, expected %q", buffer.String(), expected)
+	}
+}